@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultServer = "http://localhost:5006"
+	userAgent     = "c2v2-cli/1.0"
+	// 推送和读取的请求超时，watch 的长连接不设超时
+	requestTimeout = 30 * time.Second
+	// 服务器未指定 retry 时的重连间隔
+	defaultRetry = 3 * time.Second
+)
+
+// errRoomExpired 表示房间已到期或被删除，watch 随之结束
+var errRoomExpired = errors.New("room expired")
+
+// clipOptions 是 clip 子命令的参数，未指定时从环境变量读取
+type clipOptions struct {
+	server   string
+	room     string
+	token    string
+	password string
+	key      string
+	author   string
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// runClip 执行 clip 子命令并返回退出码
+func runClip(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "push", "pull", "watch":
+	default:
+		fmt.Fprintf(os.Stderr, "c2v2: unknown clip command %q\n\n%s", cmd, usage)
+		return 2
+	}
+
+	hostname, _ := os.Hostname()
+	var opts clipOptions
+	fs := flag.NewFlagSet("c2v2 clip "+cmd, flag.ContinueOnError)
+	fs.StringVar(&opts.server, "server", envOr("C2V2_SERVER", defaultServer), "server URL (env C2V2_SERVER)")
+	fs.StringVar(&opts.room, "room", os.Getenv("C2V2_ROOM"), "room ID or link (env C2V2_ROOM)")
+	fs.StringVar(&opts.token, "token", os.Getenv("C2V2_TOKEN"), "access token of a protected room (env C2V2_TOKEN)")
+	fs.StringVar(&opts.password, "password", os.Getenv("C2V2_PASSWORD"), "edit password of a protected room (env C2V2_PASSWORD)")
+	fs.StringVar(&opts.key, "key", os.Getenv("C2V2_KEY"), "key of an encrypted room, the part after #key= in its link (env C2V2_KEY)")
+	if cmd == "push" {
+		fs.StringVar(&opts.author, "author", envOr("C2V2_AUTHOR", hostname), "author name shown in the room history (env C2V2_AUTHOR)")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: c2v2 clip %s [flags] [room]\n\nFlags:\n", cmd)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		opts.room = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	client, err := newClipClient(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "c2v2:", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch cmd {
+	case "push":
+		err = client.push(ctx, os.Stdin)
+	case "pull":
+		err = client.pull(ctx, os.Stdout)
+	case "watch":
+		err = client.watch(ctx, os.Stdout)
+	}
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintln(os.Stderr, "c2v2:", err)
+		return 1
+	}
+	return 0
+}
+
+// clipClient 通过 HTTP 接口访问一个剪贴板房间
+type clipClient struct {
+	server   string
+	room     string
+	token    string
+	password string
+	author   string
+	// key 为空表示房间未加密（或用户没有提供密钥）
+	key  []byte
+	http *http.Client
+}
+
+// newClipClient 解析参数，房间为完整链接时从中读取服务器地址、?t= 令牌和 #key= 密钥，
+// 命令行或环境变量中显式指定的令牌和密钥优先
+func newClipClient(opts clipOptions) (*clipClient, error) {
+	c := &clipClient{
+		server:   opts.server,
+		room:     strings.TrimSpace(opts.room),
+		token:    opts.token,
+		password: opts.password,
+		author:   opts.author,
+		http:     &http.Client{},
+	}
+
+	if strings.Contains(c.room, "://") {
+		u, err := url.Parse(c.room)
+		if err != nil {
+			return nil, fmt.Errorf("invalid room link: %v", err)
+		}
+		c.server = u.Scheme + "://" + u.Host
+		c.room = u.Path[strings.LastIndex(u.Path, "/")+1:]
+		if c.token == "" {
+			c.token = u.Query().Get("t")
+		}
+		if fragment, err := url.ParseQuery(u.Fragment); err == nil && opts.key == "" {
+			opts.key = fragment.Get("key")
+		}
+	}
+	if c.room == "" || strings.ContainsAny(c.room, "/?#") {
+		return nil, errors.New("missing or invalid room, pass it as an argument, with -room or in C2V2_ROOM")
+	}
+
+	server, err := url.Parse(c.server)
+	if err != nil || server.Host == "" || (server.Scheme != "http" && server.Scheme != "https") {
+		return nil, fmt.Errorf("invalid server URL %q", c.server)
+	}
+	c.server = strings.TrimRight(c.server, "/")
+
+	if opts.key != "" {
+		if c.key, err = parseKey(opts.key); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// newRequest 创建访问房间接口的请求并附带凭据
+func (c *clipClient) newRequest(ctx context.Context, method, endpoint string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.server+"/api/clipboard/"+endpoint+"/"+url.PathEscape(c.room), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if c.token != "" {
+		req.Header.Set("X-Clipboard-Token", c.token)
+	}
+	if c.password != "" {
+		req.Header.Set("X-Clipboard-Password", c.password)
+	}
+	return req, nil
+}
+
+// statusError 是服务器返回的错误响应
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	switch e.code {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "access denied, pass the room link with its ?t= token, -token or -password"
+	case http.StatusRequestEntityTooLarge:
+		return "content too large for this room"
+	}
+	if e.message != "" {
+		return fmt.Sprintf("server returned %d: %s", e.code, e.message)
+	}
+	return fmt.Sprintf("server returned %d %s", e.code, http.StatusText(e.code))
+}
+
+// checkResponse 把非 2xx 响应转换为 statusError
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	var data struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &data) == nil {
+		message = data.Error
+	}
+	return &statusError{code: resp.StatusCode, message: message}
+}
+
+// do 发送普通请求并把 JSON 响应解码到 out
+func (c *clipClient) do(req *http.Request, out any) error {
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	defer cancel()
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// decrypt 解密加密房间的内容
+func (c *clipClient) decrypt(content string, encrypted bool) (string, error) {
+	if !encrypted {
+		return content, nil
+	}
+	if c.key == nil {
+		return "", errors.New("room is end-to-end encrypted, pass its full link or -key")
+	}
+	return openEnvelope(c.key, content)
+}
+
+// push 把 r 的全部内容保存到房间
+func (c *clipClient) push(ctx context.Context, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read input: %v", err)
+	}
+	if !utf8.Valid(data) {
+		return errors.New("input is not UTF-8 text")
+	}
+	content := string(data)
+	if c.key != nil {
+		if content, err = sealEnvelope(c.key, content); err != nil {
+			return err
+		}
+	}
+
+	body, err := json.Marshal(map[string]string{"content": content, "author": c.author})
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "save", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		Rev int64 `json:"rev"`
+	}
+	err = c.do(req, &result)
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusBadRequest && c.key == nil && strings.Contains(se.message, "envelope") {
+		return errors.New("room is end-to-end encrypted, pass its full link or -key")
+	}
+	return err
+}
+
+// pull 把房间当前内容写到 w，不追加换行，便于重定向到文件
+func (c *clipClient) pull(ctx context.Context, w io.Writer) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "get", nil)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Content   string `json:"content"`
+		Encrypted bool   `json:"encrypted"`
+		Burned    bool   `json:"burned"`
+	}
+	if err := c.do(req, &result); err != nil {
+		return err
+	}
+	content, err := c.decrypt(result.Content, result.Encrypted)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		return err
+	}
+	if result.Burned {
+		fmt.Fprintln(os.Stderr, "c2v2: burn-after-read room, it has been deleted")
+	}
+	return nil
+}
+
+// watch 持续接收房间内容，每次变化时把完整内容写到 w。
+// 连接断开后按服务器给出的间隔重连，并带上 Last-Event-ID，内容没有变化时服务器不会重复发送
+func (c *clipClient) watch(ctx context.Context, w io.Writer) error {
+	s := &sseState{retry: defaultRetry}
+	var printed *string
+
+	show := func(content string) error {
+		if printed != nil && *printed == content {
+			return nil
+		}
+		printed = &content
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		_, err := io.WriteString(w, content)
+		return err
+	}
+
+	for {
+		err := c.stream(ctx, s, show)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var se *statusError
+		if errors.Is(err, errRoomExpired) || errors.As(err, &se) && se.code < 500 || s.fatal {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "c2v2: connection lost (%v), reconnecting in %s\n", err, s.retry)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.retry):
+		}
+	}
+}
+
+// sseState 是跨重连保留的事件流状态
+type sseState struct {
+	lastID string
+	retry  time.Duration
+	// fatal 表示错误不会因重连而消失，例如缺少密钥
+	fatal bool
+}
+
+// stream 打开一次事件流并处理事件，直到连接断开
+func (c *clipClient) stream(ctx context.Context, s *sseState, show func(string) error) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "stream", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if s.lastID != "" {
+		req.Header.Set("Last-Event-ID", s.lastID)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	encrypted := false
+	dispatch := func(event, data string) error {
+		switch event {
+		case "hello":
+			var hello struct {
+				Encrypted bool `json:"encrypted"`
+			}
+			json.Unmarshal([]byte(data), &hello)
+			encrypted = hello.Encrypted
+			if encrypted && c.key == nil {
+				s.fatal = true
+				return errors.New("room is end-to-end encrypted, pass its full link or -key")
+			}
+		case "message":
+			var msg struct {
+				Content string `json:"content"`
+			}
+			if err := json.Unmarshal([]byte(data), &msg); err != nil {
+				return fmt.Errorf("invalid message event: %v", err)
+			}
+			content, err := c.decrypt(msg.Content, encrypted)
+			if err != nil {
+				s.fatal = true
+				return err
+			}
+			return show(content)
+		case "expired":
+			return errRoomExpired
+		}
+		return nil
+	}
+
+	// 按 SSE 规范逐行解析，data 行可能很长，因此不使用 bufio.Scanner
+	r := bufio.NewReader(resp.Body)
+	var event string
+	var data []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return errors.New("stream closed by server")
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if len(data) > 0 {
+				if event == "" {
+					event = "message"
+				}
+				if err := dispatch(event, strings.Join(data, "\n")); err != nil {
+					return err
+				}
+			}
+			event, data = "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			s.lastID = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
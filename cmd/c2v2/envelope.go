@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// 加密房间使用与浏览器相同的密文信封：
+//
+//	v1.<base64url(iv)>.<base64url(ciphertext+tag)>
+//
+// 密钥是房间链接 #key= 片段中的 base64url 字符串，只在本地使用，不会发送到服务器
+const (
+	envelopeVersion = "v1"
+	envelopeIVSize  = 12
+)
+
+var errBadEnvelope = errors.New("content is not a valid encrypted envelope")
+
+// parseKey 解码房间链接中的密钥
+func parseKey(s string) ([]byte, error) {
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, errors.New("invalid encryption key: not base64url")
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, errors.New("invalid encryption key length")
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealEnvelope 加密文本，空文本表示清空房间，不加密
+func sealEnvelope(key []byte, plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, envelopeIVSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	ciphertext := gcm.Seal(nil, iv, []byte(plain), nil)
+	return envelopeVersion + "." + base64.RawURLEncoding.EncodeToString(iv) + "." + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// openEnvelope 解密信封，密钥不匹配时返回错误
+func openEnvelope(key []byte, envelope string) (string, error) {
+	if envelope == "" {
+		return "", nil
+	}
+	parts := strings.Split(envelope, ".")
+	if len(parts) != 3 || parts[0] != envelopeVersion {
+		return "", errBadEnvelope
+	}
+	iv, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(iv) != envelopeIVSize {
+		return "", errBadEnvelope
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errBadEnvelope
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	plain, err := gcm.Open(nil, iv, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt room content: wrong key")
+	}
+	return string(plain), nil
+}
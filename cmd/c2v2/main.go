@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: c2v2 <command> [arguments]
+
+Commands:
+  clip push  [flags] <room>   save standard input to a clipboard room
+  clip pull  [flags] <room>   write the room content to standard output
+  clip watch [flags] <room>   print the room content every time it changes
+
+<room> is a room ID or a full room link; a link also sets the server,
+the ?t= access token and the #key= encryption key.
+Run "c2v2 clip <command> -h" for the flags of each command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "clip":
+		os.Exit(runClip(os.Args[2:]))
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "c2v2: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
@@ -2,7 +2,9 @@ package app
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config 存储应用程序配置
@@ -15,6 +17,35 @@ type Config struct {
 	SupportedLangs []string
 	// DefaultLang 是默认语言
 	DefaultLang string
+	// TrustedProxies 是可信的反向代理地址（IP 或 CIDR），只有来自这些地址的 X-Forwarded-For 才被采信
+	TrustedProxies []string
+	// ClipboardDataDir 是剪贴板房间的持久化目录，为空时仅保存在内存中
+	ClipboardDataDir string
+	// ClipboardIDLength 是随机房间 ID 的长度
+	ClipboardIDLength int
+	// ClipboardIDFormat 是默认的房间 ID 格式（random 或 words）
+	ClipboardIDFormat string
+	// ClipboardMaxFileMB 是单个附件的大小上限（MB）
+	ClipboardMaxFileMB int
+	// ClipboardRoomQuotaMB 是每个房间附件的总大小上限（MB）
+	ClipboardRoomQuotaMB int
+	// ClipboardMaxContentKB 是房间内容的大小上限（KB）
+	ClipboardMaxContentKB int
+	// ClipboardSavesPerMinute 和 ClipboardRoomsPerMinute 是每个 IP 每分钟的保存次数和新建房间数上限
+	ClipboardSavesPerMinute int
+	ClipboardRoomsPerMinute int
+	// ClipboardMaxRooms 是每个实例内存中的房间数上限，达到上限时淘汰最久未使用的房间
+	ClipboardMaxRooms int
+	// ClipboardIdleTTL 是未指定有效期的房间的空闲超时
+	ClipboardIdleTTL time.Duration
+	// ClipboardMaxTTL 是创建房间时可选择的最长有效期
+	ClipboardMaxTTL time.Duration
+	// ClipboardCleanInterval 是清理过期房间的检查间隔
+	ClipboardCleanInterval time.Duration
+	// ClipboardPubSubURL 是多实例之间同步房间的消息总线地址，为空时只在进程内同步
+	ClipboardPubSubURL string
+	// ClipboardAdminToken 是剪贴板管理和指标接口的访问令牌，为空时不开放这些接口
+	ClipboardAdminToken string
 }
 
 // DefaultConfig 返回默认配置
@@ -24,6 +55,21 @@ func DefaultConfig() *Config {
 		Port:           "5006",
 		SupportedLangs: []string{"en", "zh"},
 		DefaultLang:    "en",
+
+		ClipboardIDLength: 8,
+		ClipboardIDFormat: "random",
+
+		ClipboardMaxFileMB:    10,
+		ClipboardRoomQuotaMB:  50,
+		ClipboardMaxContentKB: 1024,
+
+		ClipboardSavesPerMinute: 120,
+		ClipboardRoomsPerMinute: 10,
+		ClipboardMaxRooms:       10000,
+
+		ClipboardIdleTTL:       30 * time.Minute,
+		ClipboardMaxTTL:        7 * 24 * time.Hour,
+		ClipboardCleanInterval: time.Minute,
 	}
 }
 
@@ -51,6 +97,87 @@ func LoadConfig() *Config {
 		cfg.DefaultLang = defaultLang
 	}
 
+	// 从环境变量读取可信的反向代理（逗号分隔）
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		cfg.TrustedProxies = strings.Split(proxies, ",")
+	}
+
+	// 从环境变量读取剪贴板数据目录
+	if dataDir := os.Getenv("CLIPBOARD_DATA_DIR"); dataDir != "" {
+		cfg.ClipboardDataDir = dataDir
+	}
+
+	// 从环境变量读取剪贴板房间 ID 长度和格式
+	if idLength := os.Getenv("CLIPBOARD_ID_LENGTH"); idLength != "" {
+		if n, err := strconv.Atoi(idLength); err == nil {
+			cfg.ClipboardIDLength = n
+		}
+	}
+	if idFormat := os.Getenv("CLIPBOARD_ID_FORMAT"); idFormat != "" {
+		cfg.ClipboardIDFormat = idFormat
+	}
+
+	// 从环境变量读取剪贴板附件大小限制
+	if maxFile := os.Getenv("CLIPBOARD_MAX_FILE_MB"); maxFile != "" {
+		if n, err := strconv.Atoi(maxFile); err == nil {
+			cfg.ClipboardMaxFileMB = n
+		}
+	}
+	if quota := os.Getenv("CLIPBOARD_ROOM_QUOTA_MB"); quota != "" {
+		if n, err := strconv.Atoi(quota); err == nil {
+			cfg.ClipboardRoomQuotaMB = n
+		}
+	}
+
+	// 从环境变量读取剪贴板内容大小和频率限制
+	if maxContent := os.Getenv("CLIPBOARD_MAX_CONTENT_KB"); maxContent != "" {
+		if n, err := strconv.Atoi(maxContent); err == nil {
+			cfg.ClipboardMaxContentKB = n
+		}
+	}
+	if saves := os.Getenv("CLIPBOARD_SAVES_PER_MINUTE"); saves != "" {
+		if n, err := strconv.Atoi(saves); err == nil {
+			cfg.ClipboardSavesPerMinute = n
+		}
+	}
+	if rooms := os.Getenv("CLIPBOARD_ROOMS_PER_MINUTE"); rooms != "" {
+		if n, err := strconv.Atoi(rooms); err == nil {
+			cfg.ClipboardRoomsPerMinute = n
+		}
+	}
+	if maxRooms := os.Getenv("CLIPBOARD_MAX_ROOMS"); maxRooms != "" {
+		if n, err := strconv.Atoi(maxRooms); err == nil {
+			cfg.ClipboardMaxRooms = n
+		}
+	}
+
+	// 从环境变量读取剪贴板房间有效期（Go duration 格式，例如 30m、168h）
+	if idleTTL := os.Getenv("CLIPBOARD_IDLE_TTL"); idleTTL != "" {
+		if d, err := time.ParseDuration(idleTTL); err == nil {
+			cfg.ClipboardIdleTTL = d
+		}
+	}
+	if maxTTL := os.Getenv("CLIPBOARD_MAX_TTL"); maxTTL != "" {
+		if d, err := time.ParseDuration(maxTTL); err == nil {
+			cfg.ClipboardMaxTTL = d
+		}
+	}
+	if interval := os.Getenv("CLIPBOARD_CLEAN_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil {
+			cfg.ClipboardCleanInterval = d
+		}
+	}
+
+	// 从环境变量读取剪贴板消息总线地址
+	if pubsubURL := os.Getenv("CLIPBOARD_PUBSUB_URL"); pubsubURL != "" {
+		cfg.ClipboardPubSubURL = pubsubURL
+	}
+
+	// 从环境变量读取剪贴板管理令牌
+	if adminToken := os.Getenv("CLIPBOARD_ADMIN_TOKEN"); adminToken != "" {
+		cfg.ClipboardAdminToken = adminToken
+	}
+
 	return cfg
 }
 
@@ -6,7 +6,9 @@ import (
 	"c2v2/internal/pkg/render"
 	"c2v2/internal/tools"
 	"html/template"
+	"log"
 	"net/http"
+	"path/filepath"
 	"strings"
 
 	"github.com/gin-contrib/gzip"
@@ -15,6 +17,10 @@ import (
 
 func SetupRouter(i18nMgr *i18n.Manager, cfg *Config) *gin.Engine {
 	r := gin.Default()
+	// 只采信可信代理转发的客户端地址，剪贴板按客户端 IP 限制频率
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("可信代理配置无效: %v", err)
+	}
 
 	// 全局中间件
 	r.Use(gzip.Gzip(gzip.DefaultCompression)) // Gzip 压缩
@@ -99,12 +105,49 @@ Sitemap: ` + domain + `/sitemap.xml
 	})
 
 	base64Tool := tools.NewBase64Tool(renderHelper)
+	encoderTool := tools.NewEncoderTool(renderHelper)
 	jsonTool := tools.NewJsonFmtTool(renderHelper)
 	htmlTool := tools.NewHTMLFmtTool(renderHelper)
 	cssTool := tools.NewCSSFmtTool(renderHelper)
 	heicTool := tools.NewHeicTool(renderHelper)
 	passwordTool := tools.NewPasswordTool(renderHelper)
-	clipboardTool := tools.NewClipboardHandler(renderHelper)
+	qrTool := tools.NewQRCodeTool(renderHelper)
+
+	// 剪贴板房间存储（配置数据目录时持久化到磁盘）
+	clipboardStore, err := tools.NewRoomStore(cfg.ClipboardDataDir)
+	if err != nil {
+		log.Fatalf("初始化剪贴板存储失败: %v", err)
+	}
+	// 附件与房间数据放在同一目录下，未配置时使用临时目录
+	attachmentDir := ""
+	if cfg.ClipboardDataDir != "" {
+		attachmentDir = filepath.Join(cfg.ClipboardDataDir, "attachments")
+	}
+	clipboardFiles, err := tools.NewAttachmentStore(attachmentDir)
+	if err != nil {
+		log.Fatalf("初始化剪贴板附件存储失败: %v", err)
+	}
+	// 多实例之间同步房间的消息总线
+	clipboardBus, err := tools.NewRoomBroadcaster(cfg.ClipboardPubSubURL)
+	if err != nil {
+		log.Fatalf("初始化剪贴板消息总线失败: %v", err)
+	}
+	clipboardTool := tools.NewClipboardHandler(renderHelper, clipboardStore, clipboardFiles, clipboardBus, tools.ClipboardConfig{
+		IDLength:    cfg.ClipboardIDLength,
+		IDFormat:    cfg.ClipboardIDFormat,
+		MaxFileSize: int64(cfg.ClipboardMaxFileMB) << 20,
+		RoomQuota:   int64(cfg.ClipboardRoomQuotaMB) << 20,
+
+		MaxContentSize: cfg.ClipboardMaxContentKB << 10,
+		SavesPerMinute: cfg.ClipboardSavesPerMinute,
+		RoomsPerMinute: cfg.ClipboardRoomsPerMinute,
+		MaxRooms:       cfg.ClipboardMaxRooms,
+		AdminToken:     cfg.ClipboardAdminToken,
+
+		IdleTTL:       cfg.ClipboardIdleTTL,
+		MaxTTL:        cfg.ClipboardMaxTTL,
+		CleanInterval: cfg.ClipboardCleanInterval,
+	})
 
 	// 从统一注册中心获取工具数据
 	categories := tools.Categories()
@@ -142,6 +185,14 @@ Sitemap: ` + domain + `/sitemap.xml
 		})
 	}
 
+	// 剪贴板管理和指标接口，需要 Authorization: Bearer <CLIPBOARD_ADMIN_TOKEN>
+	clipboardAdmin := r.Group("/api/clipboard/admin", clipboardTool.RequireAdmin)
+	{
+		clipboardAdmin.GET("/stats", clipboardTool.HandleAdminStats)
+		clipboardAdmin.GET("/metrics", clipboardTool.HandleAdminMetrics)
+		clipboardAdmin.DELETE("/rooms/:id", clipboardTool.HandleAdminDeleteRoom)
+	}
+
 	// 1. 默认语言路由 (英语)
 	defaultGroup := r.Group("")
 	defaultGroup.Use(middleware.I18nMiddleware())
@@ -162,6 +213,9 @@ Sitemap: ` + domain + `/sitemap.xml
 		// 工具路由
 		defaultGroup.GET("/base64", base64Tool.Handler)
 		defaultGroup.POST("/base64", base64Tool.Handler)
+		defaultGroup.POST("/base64/download", base64Tool.HandleDownload)
+		defaultGroup.GET("/encoder", encoderTool.Handler)
+		defaultGroup.POST("/encoder", encoderTool.Handler)
 		defaultGroup.GET("/json-fmt", jsonTool.Handler)
 		defaultGroup.POST("/json-fmt", jsonTool.Handler)
 		defaultGroup.GET("/html-fmt", htmlTool.Handler)
@@ -171,14 +225,28 @@ Sitemap: ` + domain + `/sitemap.xml
 		defaultGroup.GET("/heic-to-jpg", heicTool.Handler)
 		defaultGroup.POST("/heic-to-jpg", heicTool.Handler)
 		defaultGroup.GET("/password-generator", passwordTool.Handler)
+		defaultGroup.GET("/qr-code", qrTool.Handler)
+		defaultGroup.GET("/api/qr", qrTool.HandleImage)
 
 		// 剪贴板工具
 		defaultGroup.GET("/clipboard", clipboardTool.HandleIndex)
 		defaultGroup.GET("/clipboard/create", clipboardTool.HandleCreate)
+		defaultGroup.POST("/clipboard/create", clipboardTool.HandleCreate)
 		defaultGroup.GET("/clipboard/:id", clipboardTool.HandleRoom)
 		defaultGroup.GET("/api/clipboard/get/:id", clipboardTool.HandleGet)
 		defaultGroup.POST("/api/clipboard/save/:id", clipboardTool.HandleSave)
+		defaultGroup.POST("/api/clipboard/type/:id", clipboardTool.HandleSetType)
+		defaultGroup.GET("/api/clipboard/pretty/:id", clipboardTool.HandlePretty)
+		defaultGroup.GET("/api/clipboard/qr/:id", clipboardTool.HandleQRCode)
 		defaultGroup.GET("/api/clipboard/stream/:id", clipboardTool.HandleStream)
+		defaultGroup.GET("/api/clipboard/ws/:id", clipboardTool.HandleWebSocket)
+		defaultGroup.POST("/api/clipboard/unlock/:id", clipboardTool.HandleUnlock)
+		defaultGroup.GET("/api/clipboard/history/:id", clipboardTool.HandleHistory)
+		defaultGroup.POST("/api/clipboard/history/:id/restore", clipboardTool.HandleRestore)
+		defaultGroup.GET("/api/clipboard/files/:id", clipboardTool.HandleFiles)
+		defaultGroup.POST("/api/clipboard/files/:id", clipboardTool.HandleUpload)
+		defaultGroup.GET("/api/clipboard/files/:id/:file", clipboardTool.HandleDownload)
+		defaultGroup.DELETE("/api/clipboard/files/:id/:file", clipboardTool.HandleDeleteFile)
 
 		// 静态页面路由
 		defaultGroup.GET("/about", func(c *gin.Context) {
@@ -215,6 +283,9 @@ Sitemap: ` + domain + `/sitemap.xml
 		// 工具路由
 		langGroup.GET("/base64", base64Tool.Handler)
 		langGroup.POST("/base64", base64Tool.Handler)
+		langGroup.POST("/base64/download", base64Tool.HandleDownload)
+		langGroup.GET("/encoder", encoderTool.Handler)
+		langGroup.POST("/encoder", encoderTool.Handler)
 		langGroup.GET("/json-fmt", jsonTool.Handler)
 		langGroup.POST("/json-fmt", jsonTool.Handler)
 		langGroup.GET("/html-fmt", htmlTool.Handler)
@@ -224,14 +295,28 @@ Sitemap: ` + domain + `/sitemap.xml
 		langGroup.GET("/heic-to-jpg", heicTool.Handler)
 		langGroup.POST("/heic-to-jpg", heicTool.Handler)
 		langGroup.GET("/password-generator", passwordTool.Handler)
+		langGroup.GET("/qr-code", qrTool.Handler)
+		langGroup.GET("/api/qr", qrTool.HandleImage)
 
 		// 剪贴板工具
 		langGroup.GET("/clipboard", clipboardTool.HandleIndex)
 		langGroup.GET("/clipboard/create", clipboardTool.HandleCreate)
+		langGroup.POST("/clipboard/create", clipboardTool.HandleCreate)
 		langGroup.GET("/clipboard/:id", clipboardTool.HandleRoom)
 		langGroup.GET("/api/clipboard/get/:id", clipboardTool.HandleGet)
 		langGroup.POST("/api/clipboard/save/:id", clipboardTool.HandleSave)
+		langGroup.POST("/api/clipboard/type/:id", clipboardTool.HandleSetType)
+		langGroup.GET("/api/clipboard/pretty/:id", clipboardTool.HandlePretty)
+		langGroup.GET("/api/clipboard/qr/:id", clipboardTool.HandleQRCode)
 		langGroup.GET("/api/clipboard/stream/:id", clipboardTool.HandleStream)
+		langGroup.GET("/api/clipboard/ws/:id", clipboardTool.HandleWebSocket)
+		langGroup.POST("/api/clipboard/unlock/:id", clipboardTool.HandleUnlock)
+		langGroup.GET("/api/clipboard/history/:id", clipboardTool.HandleHistory)
+		langGroup.POST("/api/clipboard/history/:id/restore", clipboardTool.HandleRestore)
+		langGroup.GET("/api/clipboard/files/:id", clipboardTool.HandleFiles)
+		langGroup.POST("/api/clipboard/files/:id", clipboardTool.HandleUpload)
+		langGroup.GET("/api/clipboard/files/:id/:file", clipboardTool.HandleDownload)
+		langGroup.DELETE("/api/clipboard/files/:id/:file", clipboardTool.HandleDeleteFile)
 
 		// 静态页面路由
 		langGroup.GET("/about", func(c *gin.Context) {
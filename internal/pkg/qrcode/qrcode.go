@@ -0,0 +1,456 @@
+// Package qrcode 实现 QR 码（ISO/IEC 18004）编码，支持数字、字母数字和字节模式，版本 1-40，
+// 四种纠错级别，并输出 SVG 或 PNG 图片
+package qrcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Level 是纠错级别，可恢复约 7%、15%、25%、30% 的损坏
+type Level int
+
+const (
+	L Level = iota
+	M
+	Q
+	H
+)
+
+// ParseLevel 解析纠错级别字母（不区分大小写），空字符串返回 M
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return L, nil
+	case "", "M":
+		return M, nil
+	case "Q":
+		return Q, nil
+	case "H":
+		return H, nil
+	default:
+		return M, errors.New("invalid error correction level")
+	}
+}
+
+func (l Level) String() string {
+	return [...]string{"L", "M", "Q", "H"}[l]
+}
+
+// formatBits 是纠错级别在格式信息中的编码
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// ErrTooLong 表示数据超过了版本 40 在该纠错级别下的容量
+var ErrTooLong = errors.New("data too long for a QR code")
+
+// 每个块的纠错码字数和块数，按纠错级别和版本索引（版本 0 不使用）
+var (
+	eccPerBlock = [4][41]int{
+		{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+		{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	}
+	eccBlocks = [4][41]int{
+		{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+		{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+		{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+	}
+)
+
+// Code 是编码后的 QR 码，Modules[y][x] 为 true 表示深色模块
+type Code struct {
+	Version int
+	Level   Level
+	Mask    int
+	Size    int
+	Modules [][]bool
+	// function 标记定位、定时、校正图形和格式信息等功能区域，数据和掩码不覆盖这些模块
+	function [][]bool
+}
+
+// Encode 把数据编码为 QR 码，自动选择编码模式、最小的版本和惩罚分最低的掩码
+func Encode(data []byte, level Level) (*Code, error) {
+	seg := newSegment(data)
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if seg.bitLength(v) <= dataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+	return encodeVersion(seg, version, level, -1), nil
+}
+
+// encodeVersion 按指定版本编码，mask 为 -1 时自动选择掩码
+func encodeVersion(seg segment, version int, level Level, mask int) *Code {
+	capacity := dataCodewords(version, level)
+	var bb bitBuffer
+	bb.append(seg.mode.indicator, 4)
+	bb.append(seg.count, seg.mode.countBits(version))
+	bb.bits = append(bb.bits, seg.bits.bits...)
+	// 终止符最多 4 位，然后补齐到整字节，再用 0xEC、0x11 交替填充
+	bb.append(0, min(4, capacity*8-len(bb.bits)))
+	bb.append(0, (8-len(bb.bits)%8)%8)
+	for pad := 0xEC; len(bb.bits) < capacity*8; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	c := newCode(version, level)
+	c.drawFunctionPatterns()
+	c.drawCodewords(c.addECC(bb.bytes()))
+
+	if mask < 0 {
+		best := -1
+		for m := 0; m < 8; m++ {
+			c.applyMask(m)
+			c.drawFormatBits(m)
+			if score := c.penalty(); best < 0 || score < best {
+				best, mask = score, m
+			}
+			c.applyMask(m)
+		}
+	}
+	c.Mask = mask
+	c.applyMask(mask)
+	c.drawFormatBits(mask)
+	c.function = nil
+	return c
+}
+
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	c := &Code{Version: version, Level: level, Size: size}
+	c.Modules = make([][]bool, size)
+	c.function = make([][]bool, size)
+	for i := range size {
+		c.Modules[i] = make([]bool, size)
+		c.function[i] = make([]bool, size)
+	}
+	return c
+}
+
+// rawDataModules 是版本 v 中除功能图形外可用于数据和纠错码的模块数
+func rawDataModules(v int) int {
+	result := (16*v+128)*v + 64
+	if v >= 2 {
+		align := v/7 + 2
+		result -= (25*align-10)*align - 55
+		if v >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// dataCodewords 是版本 v 在纠错级别 level 下可容纳的数据码字数
+func dataCodewords(v int, level Level) int {
+	return rawDataModules(v)/8 - eccPerBlock[level][v]*eccBlocks[level][v]
+}
+
+// setFunction 设置功能模块
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.Modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	// 定时图形
+	for i := range c.Size {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	// 三个定位图形（含分隔符）
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	// 校正图形，跳过与定位图形重叠的三个角
+	pos := alignmentPositions(c.Version)
+	n := len(pos)
+	for i := range n {
+		for j := range n {
+			if i == 0 && j == 0 || i == 0 && j == n-1 || i == n-1 && j == 0 {
+				continue
+			}
+			c.drawAlignment(pos[i], pos[j])
+		}
+	}
+
+	// 先占住格式信息的位置，选择掩码后再写入
+	c.drawFormatBits(0)
+	c.drawVersion()
+}
+
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions 返回校正图形中心的行列坐标
+func alignmentPositions(v int) []int {
+	if v == 1 {
+		return nil
+	}
+	n := v/7 + 2
+	step := 26
+	if v != 32 {
+		step = (v*4 + n*2 + 1) / (n*2 - 2) * 2
+	}
+	pos := make([]int, n)
+	pos[0] = 6
+	for i, p := n-1, v*4+17-7; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return pos
+}
+
+// drawFormatBits 写入纠错级别和掩码（BCH(15,5) 编码），左上角一份，右上和左下拼成另一份
+func (c *Code) drawFormatBits(mask int) {
+	data := c.Level.formatBits()<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := range 8 {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	// 固定的深色模块
+	c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersion 为版本 7 及以上写入版本信息（BCH(18,6) 编码）
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := c.Version<<12 | rem
+	for i := range 18 {
+		dark := bit(bits, i)
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// addECC 把数据分块并计算各块的纠错码，返回交错排列后的全部码字
+func (c *Code) addECC(data []byte) []byte {
+	numBlocks := eccBlocks[c.Level][c.Version]
+	eccLen := eccPerBlock[c.Level][c.Version]
+	raw := rawDataModules(c.Version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw/numBlocks - eccLen
+
+	divisor := rsDivisor(eccLen)
+	blocks := make([][]byte, numBlocks)
+	eccs := make([][]byte, numBlocks)
+	k := 0
+	for i := range numBlocks {
+		n := shortLen
+		if i >= numShort {
+			n++
+		}
+		blocks[i] = data[k : k+n]
+		eccs[i] = rsRemainder(blocks[i], divisor)
+		k += n
+	}
+
+	result := make([]byte, 0, raw)
+	for i := 0; i <= shortLen; i++ {
+		for j := range numBlocks {
+			if i < len(blocks[j]) {
+				result = append(result, blocks[j][i])
+			}
+		}
+	}
+	for i := range eccLen {
+		for j := range numBlocks {
+			result = append(result, eccs[j][i])
+		}
+	}
+	return result
+}
+
+// drawCodewords 按之字形从右下角开始把码字写入非功能模块，剩余位保持浅色
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if upward {
+					y = c.Size - 1 - vert
+				}
+				if !c.function[y][x] && i < len(data)*8 {
+					c.Modules[y][x] = bit(int(data[i>>3]), 7-i&7)
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask 对数据模块异或掩码，再次调用可以撤销
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !c.function[y][x] {
+				c.Modules[y][x] = !c.Modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty 按标准的四条规则计算惩罚分：连续同色、2x2 同色块、类似定位图形的序列、深浅比例失衡
+func (c *Code) penalty() int {
+	n := c.Size
+	score := 0
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return c.Modules[x][y]
+		}
+		return c.Modules[y][x]
+	}
+	finderLike := [...]bool{true, false, true, true, true, false, true}
+
+	for _, vertical := range []bool{false, true} {
+		for y := range n {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			// 1:1:3:1:1 的深浅序列，一侧带 4 个浅色模块
+			for x := 0; x+7 <= n; x++ {
+				match := true
+				for k, dark := range finderLike {
+					if at(x+k, y, vertical) != dark {
+						match = false
+						break
+					}
+				}
+				if match && (c.lightRun(x-4, x, y, vertical) || c.lightRun(x+7, x+11, y, vertical)) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := range n {
+		for x := range n {
+			if c.Modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				v := c.Modules[y][x]
+				if c.Modules[y][x+1] == v && c.Modules[y+1][x] == v && c.Modules[y+1][x+1] == v {
+					score += 3
+				}
+			}
+		}
+	}
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	score += k * 10
+	return score
+}
+
+// lightRun 判断 [from, to) 范围内的模块是否都是浅色，码外的静区视为浅色
+func (c *Code) lightRun(from, to, y int, vertical bool) bool {
+	for x := from; x < to; x++ {
+		if x < 0 || x >= c.Size {
+			continue
+		}
+		dark := c.Modules[y][x]
+		if vertical {
+			dark = c.Modules[x][y]
+		}
+		if dark {
+			return false
+		}
+	}
+	return true
+}
+
+func bit(v, i int) bool {
+	return v>>i&1 != 0
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
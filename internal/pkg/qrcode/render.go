@@ -0,0 +1,62 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// DefaultQuietZone 是标准要求的静区宽度（模块数）
+const DefaultQuietZone = 4
+
+// SVG 输出边长为 size 像素的矢量图，quietZone 是四周留白的模块数。
+// 所有深色模块合并为一个 path，放大打印时边缘保持清晰
+func (c *Code) SVG(size, quietZone int) []byte {
+	n := c.Size + 2*quietZone
+	var path strings.Builder
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.Modules[y][x] {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path d="%s" fill="#000"/></svg>`, n, n, path.String())
+	return b.Bytes()
+}
+
+// PNG 输出边长为 size 像素的位图。每个模块占整数个像素以保证容易识别，
+// 除不尽的部分平均加到四周留白中；size 小于码的模块数时按每模块 1 像素输出
+func (c *Code) PNG(size, quietZone int) ([]byte, error) {
+	n := c.Size + 2*quietZone
+	scale := max(1, size/n)
+	size = max(size, n*scale)
+	offset := (size - c.Size*scale) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.Modules[y][x] {
+				continue
+			}
+			for dy := range scale {
+				row := img.Pix[(offset+y*scale+dy)*img.Stride:]
+				for dx := range scale {
+					row[offset+x*scale+dx] = 1
+				}
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,143 @@
+package qrcode
+
+import "strings"
+
+// alphanumericChars 是字母数字模式的字符集，字符的值即其下标
+const alphanumericChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// mode 是数据编码模式
+type mode struct {
+	indicator int
+	// counts 是版本 1-9、10-26、27-40 的字符数字段位数
+	counts [3]int
+}
+
+var (
+	modeNumeric      = mode{0x1, [3]int{10, 12, 14}}
+	modeAlphanumeric = mode{0x2, [3]int{9, 11, 13}}
+	modeByte         = mode{0x4, [3]int{8, 16, 16}}
+)
+
+func (m mode) countBits(version int) int {
+	switch {
+	case version <= 9:
+		return m.counts[0]
+	case version <= 26:
+		return m.counts[1]
+	default:
+		return m.counts[2]
+	}
+}
+
+// segment 是一段按同一模式编码的数据
+type segment struct {
+	mode  mode
+	count int
+	bits  bitBuffer
+}
+
+// newSegment 选择能容纳全部数据的最紧凑模式：纯数字、大写字母数字，否则按字节（UTF-8）编码
+func newSegment(data []byte) segment {
+	s := string(data)
+	switch {
+	case len(s) > 0 && strings.Trim(s, "0123456789") == "":
+		seg := segment{mode: modeNumeric, count: len(s)}
+		for i := 0; i < len(s); i += 3 {
+			chunk := s[i:min(i+3, len(s))]
+			n := 0
+			for _, ch := range chunk {
+				n = n*10 + int(ch-'0')
+			}
+			seg.bits.append(n, len(chunk)*3+1)
+		}
+		return seg
+	case len(s) > 0 && strings.Trim(s, alphanumericChars) == "":
+		seg := segment{mode: modeAlphanumeric, count: len(s)}
+		for i := 0; i+1 < len(s); i += 2 {
+			seg.bits.append(strings.IndexByte(alphanumericChars, s[i])*45+strings.IndexByte(alphanumericChars, s[i+1]), 11)
+		}
+		if len(s)%2 == 1 {
+			seg.bits.append(strings.IndexByte(alphanumericChars, s[len(s)-1]), 6)
+		}
+		return seg
+	default:
+		seg := segment{mode: modeByte, count: len(data)}
+		for _, b := range data {
+			seg.bits.append(int(b), 8)
+		}
+		return seg
+	}
+}
+
+// bitLength 是该段在版本 version 中占用的位数，字符数超出字段范围时返回一个不可能容纳的长度
+func (s segment) bitLength(version int) int {
+	countBits := s.mode.countBits(version)
+	if s.count >= 1<<countBits {
+		return 1 << 30
+	}
+	return 4 + countBits + len(s.bits.bits)
+}
+
+// bitBuffer 是按位追加的缓冲区
+type bitBuffer struct {
+	bits []bool
+}
+
+// append 追加 v 的低 n 位，高位在前
+func (b *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		b.bits = append(b.bits, bit(v, i))
+	}
+}
+
+// bytes 把位序列打包为字节，长度需为 8 的倍数
+func (b *bitBuffer) bytes() []byte {
+	out := make([]byte, len(b.bits)/8)
+	for i, v := range b.bits {
+		if v {
+			out[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return out
+}
+
+// rsDivisor 返回 degree 次 Reed-Solomon 生成多项式的系数（最高次项系数 1 省略）
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range degree {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder 计算数据除以生成多项式的余数，即纠错码字
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply 是 GF(2^8) 上的乘法，约化多项式为 x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
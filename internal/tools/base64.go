@@ -2,11 +2,203 @@ package tools
 
 import (
 	"c2v2/internal/pkg/render"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Base64 的变体
+const (
+	b64Auto     = "auto"     // 解码时自动识别
+	b64Standard = "standard" // RFC 4648 标准字母表，带填充
+	b64URL      = "url"      // URL 安全字母表（- 和 _），带填充
+	b64Raw      = "raw"      // 标准字母表，不带填充
+	b64RawURL   = "raw-url"  // URL 安全字母表，不带填充，例如 JWT
+	b64MIME     = "mime"     // 标准字母表，每 76 个字符换行（RFC 2045）
+)
+
+const (
+	// mimeLineLength 是 MIME 编码每行的字符数
+	mimeLineLength = 76
+	// maxInvalidOffsets 是错误信息中最多列出的非法字符数
+	maxInvalidOffsets = 10
+	// binaryPreviewSize 是二进制解码结果以十六进制显示的最大字节数
+	binaryPreviewSize = 4096
+)
+
+var errInvalidVariant = errors.New("invalid base64 variant")
+
+// base64Error 描述无法解码的输入，Offsets 是出错字符在原始输入中的字节偏移。
+// Length 为 true 表示字符都合法，但长度或填充不正确，Offsets 只有出错的位置
+type base64Error struct {
+	Offsets []int
+	Length  bool
+}
+
+func (e *base64Error) Error() string {
+	if e.Length {
+		return fmt.Sprintf("invalid base64 length or padding at offset %d", e.Offsets[0])
+	}
+	return fmt.Sprintf("invalid base64 character at offset %d", e.Offsets[0])
+}
+
+// base64Encoding 返回变体使用的编码
+func base64Encoding(variant string) (*base64.Encoding, error) {
+	switch variant {
+	case b64Standard, b64MIME:
+		return base64.StdEncoding, nil
+	case b64URL:
+		return base64.URLEncoding, nil
+	case b64Raw:
+		return base64.RawStdEncoding, nil
+	case b64RawURL:
+		return base64.RawURLEncoding, nil
+	}
+	return nil, errInvalidVariant
+}
+
+// encodeBase64 按变体编码，自动模式使用标准编码
+func encodeBase64(data []byte, variant string) (string, error) {
+	if variant == b64Auto || variant == "" {
+		variant = b64Standard
+	}
+	enc, err := base64Encoding(variant)
+	if err != nil {
+		return "", err
+	}
+	s := enc.EncodeToString(data)
+	if variant != b64MIME {
+		return s, nil
+	}
+	var b strings.Builder
+	for len(s) > mimeLineLength {
+		b.WriteString(s[:mimeLineLength])
+		b.WriteString("\r\n")
+		s = s[mimeLineLength:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
+
+// decodeBase64 解码输入并返回实际使用的变体。输入中的空白（包括 MIME 换行）会被忽略；
+// variant 为自动时根据字母表、填充和换行识别变体
+func decodeBase64(input, variant string) ([]byte, string, error) {
+	// 去掉空白，记录每个字符在原始输入中的位置。非 ASCII 字符直接记为非法
+	compact := make([]byte, 0, len(input))
+	positions := make([]int, 0, len(input))
+	var invalid []int
+	wrapped := false
+	for i, r := range input {
+		switch {
+		case unicode.IsSpace(r):
+			if r == '\n' && len(compact) > 0 {
+				wrapped = true
+			}
+		case r >= utf8.RuneSelf:
+			invalid = append(invalid, i)
+		default:
+			compact = append(compact, byte(r))
+			positions = append(positions, i)
+		}
+	}
+	// 只有末尾的换行不算分行
+	wrapped = wrapped && strings.ContainsRune(strings.TrimRightFunc(input, unicode.IsSpace), '\n')
+
+	if variant == b64Auto || variant == "" {
+		variant = detectBase64Variant(compact, wrapped)
+	}
+	enc, err := base64Encoding(variant)
+	if err != nil {
+		return nil, variant, err
+	}
+
+	// 列出所有不在字母表中的字符，填充只能出现在末尾
+	alphabet := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	if variant == b64URL || variant == b64RawURL {
+		alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	}
+	padded := variant != b64Raw && variant != b64RawURL
+	padStart := len(strings.TrimRight(string(compact), "="))
+	for i, ch := range compact {
+		if strings.IndexByte(alphabet, ch) >= 0 || (padded && ch == '=' && i >= padStart) {
+			continue
+		}
+		invalid = append(invalid, positions[i])
+	}
+	if len(invalid) > 0 {
+		slices.Sort(invalid)
+		return nil, variant, &base64Error{Offsets: invalid[:min(len(invalid), maxInvalidOffsets)]}
+	}
+
+	data := make([]byte, enc.DecodedLen(len(compact)))
+	n, err := enc.Decode(data, compact)
+	if err != nil {
+		offset := len(input)
+		var corrupt base64.CorruptInputError
+		if errors.As(err, &corrupt) && int(corrupt) < len(positions) {
+			offset = positions[corrupt]
+		}
+		return nil, variant, &base64Error{Offsets: []int{offset}, Length: true}
+	}
+	return data[:n], variant, nil
+}
+
+// detectBase64Variant 识别变体：出现 - 或 _ 的是 URL 安全字母表（以最先出现的区分字符为准），
+// 没有填充且长度不是 4 的倍数的是无填充变体，分多行的标准编码视为 MIME
+func detectBase64Variant(compact []byte, wrapped bool) string {
+	url := false
+	if i := strings.IndexAny(string(compact), "+/-_"); i >= 0 {
+		url = compact[i] == '-' || compact[i] == '_'
+	}
+	raw := !slices.Contains(compact, '=') && len(compact)%4 != 0
+	switch {
+	case url && raw:
+		return b64RawURL
+	case url:
+		return b64URL
+	case raw:
+		return b64Raw
+	case wrapped:
+		return b64MIME
+	}
+	return b64Standard
+}
+
+// isBinary 判断数据是否不能作为文本显示：不是合法的 UTF-8，或含有制表、换行以外的控制字符
+func isBinary(data []byte) bool {
+	if !utf8.Valid(data) {
+		return true
+	}
+	for _, r := range string(data) {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return true
+		}
+	}
+	return false
+}
+
+// formatOffsets 把非法字符的位置格式化为 "12 ('!'), 40 ('é')"
+func formatOffsets(input string, offsets []int) string {
+	parts := make([]string, len(offsets))
+	for i, offset := range offsets {
+		if offset >= len(input) {
+			parts[i] = fmt.Sprint(offset)
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(input[offset:])
+		parts[i] = fmt.Sprintf("%d (%q)", offset, r)
+	}
+	return strings.Join(parts, ", ")
+}
+
 type Base64Tool struct {
 	Render *render.Helper
 }
@@ -21,6 +213,12 @@ func (t *Base64Tool) Handler(c *gin.Context) {
 		lang = "en"
 	}
 
+	// HTMX 请求处理
+	if c.GetHeader("HX-Request") == "true" {
+		t.handleConvert(c, lang)
+		return
+	}
+
 	// 完整页面渲染
 
 	// 1. SoftwareApplication Schema
@@ -72,3 +270,76 @@ func (t *Base64Tool) Handler(c *gin.Context) {
 		"SchemaData":  graphSchema,
 	})
 }
+
+// handleConvert 处理编码和解码请求，返回结果片段。文本按变体编码解码，
+// 上传的文件可以编码为 Base64 或 data URI，解码时也接受 data URI
+func (t *Base64Tool) handleConvert(c *gin.Context, lang string) {
+	if !t.limitRequest(c, lang) {
+		return
+	}
+	input := c.PostForm("input")
+	variant := c.DefaultPostForm("variant", b64Auto)
+	data := gin.H{}
+
+	var result string
+	var err error
+	switch action := c.PostForm("action"); action {
+	case "encode_file", "data_uri":
+		var content []byte
+		var name string
+		content, name, err = readUpload(c)
+		if err != nil {
+			break
+		}
+		mimeType := detectMIME(content, name)
+		data["mime"] = mimeType
+		if action == "data_uri" {
+			result = encodeDataURI(content, mimeType)
+		} else {
+			result, err = encodeBase64(content, variant)
+		}
+	case "decode":
+		if strings.TrimSpace(input) == "" {
+			result = t.Render.Translate(lang, "b64_error_empty")
+			data["isError"] = true
+			break
+		}
+		var payload *base64Payload
+		payload, err = decodeInput(input, variant)
+		if err != nil {
+			break
+		}
+		if payload.Variant != "" {
+			data["variant"] = "b64_variant_" + strings.ReplaceAll(payload.Variant, "-", "_")
+		}
+		if payload.DataURI {
+			data["mime"] = payload.MIME
+		}
+		if isBinary(payload.Data) {
+			// 二进制数据以十六进制显示，过大时只显示开头部分，同时给出识别的文件类型
+			result = hex.Dump(payload.Data[:min(len(payload.Data), binaryPreviewSize)])
+			data["mime"] = payload.MIME
+			data["binary"] = true
+			data["binarySize"] = len(payload.Data)
+			data["truncated"] = len(payload.Data) > binaryPreviewSize
+		} else {
+			result = string(payload.Data)
+		}
+	default:
+		if strings.TrimSpace(input) == "" {
+			result = t.Render.Translate(lang, "b64_error_empty")
+			data["isError"] = true
+			break
+		}
+		result, err = encodeBase64([]byte(input), variant)
+	}
+	if err != nil {
+		result = t.errorMessage(lang, input, err)
+		data = gin.H{"isError": true}
+	}
+
+	data["result"] = result
+	data["charCount"] = utf8.RuneCountInString(result)
+	data["byteCount"] = len(result)
+	t.Render.HTML(c, http.StatusOK, "base64_result.html", data)
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxBase64FileSize 是上传编码的文件大小上限，证书和内联到 CSS 的小图片远小于这个值
+	maxBase64FileSize = 5 << 20
+	// maxBase64Request 是编码解码请求的大小上限，需要容纳文件编码后的文本
+	maxBase64Request = 16 << 20
+)
+
+var (
+	errNoFile         = errors.New("no file uploaded")
+	errInvalidDataURI = errors.New("invalid data URI")
+)
+
+// mimeExtensions 是下载解码文件时 MIME 类型对应的扩展名，未列出的类型使用系统的映射
+var mimeExtensions = map[string]string{
+	"image/png":                ".png",
+	"image/jpeg":               ".jpg",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/bmp":                ".bmp",
+	"image/x-icon":             ".ico",
+	"image/svg+xml":            ".svg",
+	"application/pdf":          ".pdf",
+	"application/zip":          ".zip",
+	"application/x-gzip":       ".gz",
+	"application/json":         ".json",
+	"application/wasm":         ".wasm",
+	"application/x-pem-file":   ".pem",
+	"application/pkix-cert":    ".cer",
+	"application/octet-stream": ".bin",
+	"font/woff":                ".woff",
+	"font/woff2":               ".woff2",
+	"text/plain":               ".txt",
+	"text/html":                ".html",
+	"text/css":                 ".css",
+	"text/xml":                 ".xml",
+	"text/javascript":          ".js",
+}
+
+// extensionTypes 是内容无法识别时按文件扩展名判断的类型，主要是证书和文本格式的资源
+var extensionTypes = map[string]string{
+	".pem":  "application/x-pem-file",
+	".crt":  "application/x-pem-file",
+	".key":  "application/x-pem-file",
+	".cer":  "application/pkix-cert",
+	".der":  "application/pkix-cert",
+	".svg":  "image/svg+xml",
+	".css":  "text/css",
+	".js":   "text/javascript",
+	".json": "application/json",
+}
+
+// detectMIME 根据内容识别 MIME 类型；内容只能识别为普通文本或未知二进制时，参考文件扩展名
+func detectMIME(data []byte, filename string) string {
+	detected := http.DetectContentType(data)
+	base, _, _ := strings.Cut(detected, ";")
+	if base != "application/octet-stream" && base != "text/plain" && base != "text/xml" {
+		return detected
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if t, ok := extensionTypes[ext]; ok {
+		// DER 证书也可能以 .crt 结尾，二进制内容不按 PEM 处理
+		if t != "application/x-pem-file" || base != "application/octet-stream" {
+			return t
+		}
+	}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("-----BEGIN ")):
+		return "application/x-pem-file"
+	case base == "text/xml" && bytes.Contains(trimmed, []byte("<svg")):
+		return "image/svg+xml"
+	case base == "application/octet-stream" && ext != "":
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t
+		}
+	}
+	return detected
+}
+
+// extensionFor 返回 MIME 类型对应的文件扩展名
+func extensionFor(mimeType string) string {
+	base, _, _ := strings.Cut(mimeType, ";")
+	base = strings.ToLower(strings.TrimSpace(base))
+	if ext, ok := mimeExtensions[base]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(base); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// base64Payload 是解码后的数据
+type base64Payload struct {
+	Data []byte
+	MIME string
+	// Variant 是实际使用的 Base64 变体，百分号编码的 data URI 没有变体
+	Variant string
+	// DataURI 表示输入是 data URI，MIME 类型取自其声明
+	DataURI bool
+}
+
+// decodeInput 解码 Base64 文本或 data:[<mime>][;base64],<data> 格式的 data URI。
+// 错误中的偏移相对于完整输入
+func decodeInput(input, variant string) (*base64Payload, error) {
+	trimmed := strings.TrimLeftFunc(input, func(r rune) bool { return r == ' ' || r == '\t' || r == '\r' || r == '\n' })
+	if !strings.HasPrefix(strings.ToLower(trimmed), "data:") {
+		data, used, err := decodeBase64(input, variant)
+		if err != nil {
+			return nil, err
+		}
+		return &base64Payload{Data: data, MIME: detectMIME(data, ""), Variant: used}, nil
+	}
+
+	header, payload, ok := strings.Cut(trimmed, ",")
+	if !ok {
+		return nil, errInvalidDataURI
+	}
+	mediaType := header[len("data:"):]
+	encoded := strings.HasSuffix(strings.ToLower(mediaType), ";base64")
+	if encoded {
+		mediaType = mediaType[:len(mediaType)-len(";base64")]
+	}
+
+	result := &base64Payload{DataURI: true}
+	if encoded {
+		data, used, err := decodeBase64(payload, variant)
+		var b64Err *base64Error
+		if errors.As(err, &b64Err) {
+			start := len(input) - len(payload)
+			for i := range b64Err.Offsets {
+				b64Err.Offsets[i] += start
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Data, result.Variant = data, used
+	} else {
+		data, err := url.PathUnescape(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, errInvalidDataURI
+		}
+		result.Data = []byte(data)
+	}
+
+	// 省略类型时默认为 text/plain（RFC 2397），无法解析的类型按内容识别
+	result.MIME = "text/plain;charset=US-ASCII"
+	if mediaType != "" {
+		if base, params, err := mime.ParseMediaType(mediaType); err == nil {
+			result.MIME = mime.FormatMediaType(base, params)
+		} else {
+			result.MIME = detectMIME(result.Data, "")
+		}
+	}
+	return result, nil
+}
+
+// encodeDataURI 把数据编码为 data:<mime>;base64,<data>
+func encodeDataURI(data []byte, mimeType string) string {
+	return "data:" + strings.ReplaceAll(mimeType, " ", "") + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// readUpload 读取上传的文件，返回内容和文件名
+func readUpload(c *gin.Context) ([]byte, string, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", errNoFile
+	}
+	if header.Size > maxBase64FileSize {
+		return nil, "", errFileTooLarge
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxBase64FileSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxBase64FileSize {
+		return nil, "", errFileTooLarge
+	}
+	return data, filepath.Base(header.Filename), nil
+}
+
+// limitRequest 限制编码解码请求的大小，超出时写入错误并返回 false
+func (t *Base64Tool) limitRequest(c *gin.Context, lang string) bool {
+	if c.Request.ContentLength > maxBase64Request {
+		c.String(http.StatusRequestEntityTooLarge, t.Render.Translate(lang, "b64_error_file_too_large"))
+		return false
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBase64Request)
+	return true
+}
+
+// errorMessage 把编码解码错误转换为提示信息
+func (t *Base64Tool) errorMessage(lang, input string, err error) string {
+	var b64Err *base64Error
+	switch {
+	case errors.As(err, &b64Err) && b64Err.Length:
+		return t.Render.Translate(lang, "b64_error_length") + " " + formatOffsets(input, b64Err.Offsets)
+	case errors.As(err, &b64Err):
+		return t.Render.Translate(lang, "b64_error_offsets") + " " + formatOffsets(input, b64Err.Offsets)
+	case errors.Is(err, errInvalidVariant):
+		return t.Render.Translate(lang, "b64_error_variant")
+	case errors.Is(err, errInvalidDataURI):
+		return t.Render.Translate(lang, "b64_error_data_uri")
+	case errors.Is(err, errNoFile):
+		return t.Render.Translate(lang, "b64_error_no_file")
+	case errors.Is(err, errFileTooLarge):
+		return t.Render.Translate(lang, "b64_error_file_too_large")
+	}
+	return t.Render.Translate(lang, "b64_error_invalid")
+}
+
+// HandleDownload 解码 Base64 或 data URI，以文件形式下载，扩展名由 MIME 类型决定
+func (t *Base64Tool) HandleDownload(c *gin.Context) {
+	lang := c.GetString("lang")
+	if lang == "" {
+		lang = "en"
+	}
+	if !t.limitRequest(c, lang) {
+		return
+	}
+
+	input := c.PostForm("input")
+	if strings.TrimSpace(input) == "" {
+		c.String(http.StatusBadRequest, t.Render.Translate(lang, "b64_error_empty"))
+		return
+	}
+	payload, err := decodeInput(input, c.DefaultPostForm("variant", b64Auto))
+	if err != nil {
+		c.String(http.StatusBadRequest, t.errorMessage(lang, input, err))
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "decoded" + extensionFor(payload.MIME),
+	}))
+	c.Data(http.StatusOK, payload.MIME, payload.Data)
+}
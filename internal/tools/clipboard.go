@@ -1,42 +1,320 @@
 package tools
 
 import (
+	"bytes"
 	"c2v2/internal/pkg/render"
-	"math/rand"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// sseEventBuffer 是 SSE 客户端的事件缓冲，溢出时改为补发当前状态
+	sseEventBuffer = 16
+	// sseRetryMillis 是建议浏览器断线后等待的重连间隔
+	sseRetryMillis = 3000
+	// roomLookupTimeout 是向其他实例查询本地没有的房间时等待回复的时间
+	roomLookupTimeout = 500 * time.Millisecond
+	// roomPersistDelay 是 WebSocket 操作产生的修改写入存储并发布快照的延迟，连续输入期间最多每隔这么久写一次
+	roomPersistDelay = 2 * time.Second
+)
+
 type RealRoom struct {
-	ID         string
-	Content    string
+	ID      string
+	Content string
+	// Format 是内容的类型（文本、代码、JSON 或图片），随内容一起记录在修订和历史版本中
+	Format     ContentFormat
 	LastActive time.Time
-	Clients    map[chan string]bool
-	Mu         sync.Mutex
+	// Encrypted 表示房间内容是客户端加密的密文信封，创建后不可更改
+	Encrypted bool
+	// PasswordHash 非空时房间受密码保护，编辑需要密码或 EditToken，查看需要 ViewToken
+	PasswordHash []byte
+	EditToken    string
+	ViewToken    string
+	// verifiedPassword 是最近校验通过的密码的 SHA-256，重复携带密码的请求不再计算 bcrypt
+	verifiedPassword []byte
+	// Revision 在每次内容变化时递增，History 保存被替换的旧版本
+	Revision  int64
+	UpdatedAt time.Time
+	Author    string
+	History   []RoomRevision
+	// session 是最后一次修改的会话，用于合并同一会话的连续保存，不持久化
+	session string
+	// Ops 是最近的内容操作日志（不持久化），seqs 记录各 WebSocket 会话最后应用的操作序号
+	Ops  []*RoomOp
+	seqs map[string]int64
+	// persistTimer 非空时有尚未写入存储的操作，到期后保存完整快照
+	persistTimer *time.Timer
+	// Attachments 是房间附件的元数据，文件内容由 AttachmentStore 保存
+	Attachments []Attachment
+	// ExpiresAt 非零时房间到期即删除，否则在空闲超时后清理
+	ExpiresAt time.Time
+	// BurnAfterRead 房间的内容在创建者以外的人第一次读取后即删除，CreatorToken 用于识别创建者
+	BurnAfterRead bool
+	CreatorToken  string
+	// Clients 是当前连接的 SSE 和 WebSocket 客户端，peers 是其他实例上连接的客户端
+	Clients map[chan RoomEvent]*RoomClient
+	peers   map[string][]*RoomClient
+	// relay 把房间的变化发布给其他实例
+	relay func(RoomMessage)
+	Mu    sync.Mutex
+}
+
+// RoomEvent 是推送给 SSE 和 WebSocket 客户端的事件
+type RoomEvent struct {
+	Name string
+	Data any
+	// Sender 是触发事件的 WebSocket 客户端 ID，该客户端不会收到自己的事件
+	Sender string
+	// Op 是内容变化对应的操作日志记录，WebSocket 客户端据此增量更新
+	Op *RoomOp
+}
+
+// RoomOptions 是创建房间时可选择的模式
+type RoomOptions struct {
+	Encrypted    bool
+	PasswordHash []byte
+	// TTL 为 0 时使用空闲过期
+	TTL           time.Duration
+	BurnAfterRead bool
+}
+
+func newRealRoom(id string, opts RoomOptions) *RealRoom {
+	now := time.Now()
+	room := &RealRoom{
+		ID:            id,
+		Format:        textFormat,
+		LastActive:    now,
+		Encrypted:     opts.Encrypted,
+		PasswordHash:  opts.PasswordHash,
+		BurnAfterRead: opts.BurnAfterRead,
+		Clients:       make(map[chan RoomEvent]*RoomClient),
+	}
+	if room.Protected() {
+		room.EditToken = randomToken()
+		room.ViewToken = randomToken()
+	}
+	if opts.TTL > 0 {
+		room.ExpiresAt = now.Add(opts.TTL)
+	}
+	if room.BurnAfterRead {
+		room.CreatorToken = randomToken()
+	}
+	return room
+}
+
+// snapshot 返回房间的持久化快照，调用方需持有 r.Mu
+func (r *RealRoom) snapshot() RoomSnapshot {
+	return RoomSnapshot{
+		ID:            r.ID,
+		Content:       r.Content,
+		Format:        r.Format,
+		LastActive:    r.LastActive,
+		Encrypted:     r.Encrypted,
+		PasswordHash:  r.PasswordHash,
+		EditToken:     r.EditToken,
+		ViewToken:     r.ViewToken,
+		Revision:      r.Revision,
+		UpdatedAt:     r.UpdatedAt,
+		Author:        r.Author,
+		History:       r.History,
+		Attachments:   r.Attachments,
+		ExpiresAt:     r.ExpiresAt,
+		BurnAfterRead: r.BurnAfterRead,
+		CreatorToken:  r.CreatorToken,
+	}
+}
+
+// loadSettings 从快照恢复房间的设置和附件，只在重建房间时调用，调用方需持有 r.Mu
+func (r *RealRoom) loadSettings(snap RoomSnapshot) {
+	r.Encrypted = snap.Encrypted
+	r.PasswordHash = snap.PasswordHash
+	r.EditToken = snap.EditToken
+	r.ViewToken = snap.ViewToken
+	r.Attachments = snap.Attachments
+	r.ExpiresAt = snap.ExpiresAt
+	r.BurnAfterRead = snap.BurnAfterRead
+	r.CreatorToken = snap.CreatorToken
+}
+
+// sameSettings 表示快照与房间的创建设置一致。密码、令牌和加密等设置只在创建时确定，
+// 不一致的快照来自其他实例上同 ID 的另一个房间，不能用来修改本房间。调用方需持有 r.Mu
+func (r *RealRoom) sameSettings(snap RoomSnapshot) bool {
+	return r.Encrypted == snap.Encrypted &&
+		bytes.Equal(r.PasswordHash, snap.PasswordHash) &&
+		r.EditToken == snap.EditToken &&
+		r.ViewToken == snap.ViewToken &&
+		r.BurnAfterRead == snap.BurnAfterRead &&
+		r.CreatorToken == snap.CreatorToken
+}
+
+// roomFromSnapshot 用持久化的快照重建房间
+func roomFromSnapshot(snap RoomSnapshot) *RealRoom {
+	room := newRealRoom(snap.ID, RoomOptions{})
+	room.loadSettings(snap)
+	room.loadContent(snap)
+	room.LastActive = snap.LastActive
+	return room
+}
+
+// loadContent 从快照恢复房间内容及历史版本，调用方需持有 r.Mu
+func (r *RealRoom) loadContent(snap RoomSnapshot) {
+	r.Content = snap.Content
+	r.Format = snap.Format.orText()
+	r.Revision = snap.Revision
+	r.UpdatedAt = snap.UpdatedAt
+	r.Author = snap.Author
+	r.History = snap.History
+	// 其他实例或快照中的修改不属于本实例的任何会话
+	r.session = ""
+}
+
+// broadcast 把内容变化推送给房间内所有客户端，调用方需持有 r.Mu
+// SSE 客户端收到完整内容，WebSocket 客户端收到操作（提交者收到确认）
+func (r *RealRoom) broadcast(entry *RoomOp, sender string) {
+	r.broadcastEvent(RoomEvent{
+		Name:   "message",
+		Data:   r.contentData(),
+		Sender: sender,
+		Op:     entry,
+	})
+}
+
+// broadcastEvent 把事件推送给本实例和其他实例上的客户端，调用方需持有 r.Mu
+func (r *RealRoom) broadcastEvent(ev RoomEvent) {
+	r.deliver(ev)
+	r.relayEvent(ev)
+}
+
+// deliver 把事件推送给本实例的客户端，调用方需持有 r.Mu
+func (r *RealRoom) deliver(ev RoomEvent) {
+	for clientChan, client := range r.Clients {
+		select {
+		case clientChan <- ev:
+		default:
+			// 客户端处理不过来，丢弃事件并做标记，由它的发送循环补发当前状态
+			client.lagged.Store(true)
+		}
+	}
+}
+
+// resyncEvents 返回客户端丢失事件后需要补发的当前状态，调用方需持有 r.Mu
+func (r *RealRoom) resyncEvents() []RoomEvent {
+	return []RoomEvent{
+		{Name: "message", Data: r.contentData()},
+		r.filesEvent(),
+		{Name: "presence", Data: r.presence()},
+	}
+}
+
+// messageRev 返回内容事件对应的修订号
+func messageRev(ev RoomEvent) int64 {
+	if ev.Op != nil {
+		return ev.Op.Rev
+	}
+	if data, ok := ev.Data.(gin.H); ok {
+		rev, _ := data["rev"].(int64)
+		return rev
+	}
+	return 0
 }
 
 type RealRoomManager struct {
 	Rooms map[string]*RealRoom
-	Mu    sync.RWMutex
+	Store RoomStore
+	Files *AttachmentStore
+	// Bus 在多个实例之间同步房间，origin 是本实例在总线上的 ID
+	Bus    RoomBroadcaster
+	origin string
+	// wants 是正在向其他实例查询的房间，收到快照时关闭对应的通道，由 Mu 保护
+	wants map[string]chan struct{}
+	// IdleTTL 是未指定有效期的房间的空闲超时，CleanInterval 是清理检查间隔
+	IdleTTL       time.Duration
+	CleanInterval time.Duration
+	// MaxRooms 是内存中的房间数上限，达到上限时淘汰最久未使用的房间，0 表示不限制
+	MaxRooms int
+	Metrics  ClipboardMetrics
+	Mu       sync.RWMutex
 }
 
-func NewRealRoomManager() *RealRoomManager {
-	m := &RealRoomManager{Rooms: make(map[string]*RealRoom)}
+// NewRealRoomManager 创建房间管理器，从 store 恢复未过期的房间，并订阅其他实例的变化
+func NewRealRoomManager(store RoomStore, files *AttachmentStore, bus RoomBroadcaster, idleTTL, cleanInterval time.Duration, maxRooms int) *RealRoomManager {
+	m := &RealRoomManager{
+		Rooms:         make(map[string]*RealRoom),
+		Store:         store,
+		Files:         files,
+		Bus:           bus,
+		origin:        randomToken(),
+		wants:         make(map[string]chan struct{}),
+		IdleTTL:       idleTTL,
+		CleanInterval: cleanInterval,
+		MaxRooms:      maxRooms,
+	}
+	m.restore()
+	if err := bus.Subscribe(m.receive); err != nil {
+		log.Printf("订阅剪贴板消息失败: %v", err)
+	}
 	go m.cleaner()
 	return m
 }
 
+func (m *RealRoomManager) restore() {
+	snaps, err := m.Store.Load()
+	if err != nil {
+		log.Printf("恢复剪贴板房间失败: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, snap := range snaps {
+		room := roomFromSnapshot(snap)
+		if now.After(room.expiresAt(m.IdleTTL)) {
+			m.deleteStored(snap.ID)
+			continue
+		}
+		m.add(room)
+	}
+	if m.MaxRooms > 0 && len(m.Rooms) > m.MaxRooms {
+		m.evictLocked(m.MaxRooms)
+	}
+	// 清理已过期或未持久化房间遗留的附件。多实例部署时附件目录是共享的，
+	// 其他实例上的房间不在本实例的内存中，此时不能据此删除
+	if _, single := m.Bus.(*MemoryBroadcaster); single {
+		if err := m.Files.Prune(m.Rooms); err != nil {
+			log.Printf("清理剪贴板附件失败: %v", err)
+		}
+	}
+	if len(m.Rooms) > 0 {
+		log.Printf("已恢复 %d 个剪贴板房间", len(m.Rooms))
+	}
+}
+
+// cleaner 定期删除过期房间：固定有效期到期的房间即使仍有连接也会删除，
+// 空闲过期的房间只在没有客户端连接时删除。多实例部署时空闲只是本实例的判断（其他实例上可能仍有访问），
+// 因此只把房间移出内存，存储和共享的附件保留
 func (m *RealRoomManager) cleaner() {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(m.CleanInterval)
 	for range ticker.C {
+		now := time.Now()
 		m.Mu.Lock()
-		for id, room := range m.Rooms {
+		for _, room := range m.Rooms {
 			room.Mu.Lock()
-			if time.Since(room.LastActive) > 30*time.Minute && len(room.Clients) == 0 {
-				delete(m.Rooms, id)
+			switch {
+			case room.fixedExpired(now):
+				m.removeLocked(room)
+				m.Metrics.Expired.Add(1)
+			case now.After(room.expiresAt(m.IdleTTL)) && len(room.Clients) == 0:
+				if m.shared() {
+					m.unloadLocked(room)
+				} else {
+					m.removeLocked(room)
+				}
+				m.Metrics.IdleExpired.Add(1)
 			}
 			room.Mu.Unlock()
 		}
@@ -44,53 +322,313 @@ func (m *RealRoomManager) cleaner() {
 	}
 }
 
-func (m *RealRoomManager) GetRoom(id string) *RealRoom {
+// persist 将房间当前状态写入 store 并发布给其他实例，调用方需持有 room.Mu 以保证写入顺序
+func (m *RealRoomManager) persist(room *RealRoom) {
+	room.cancelPersist()
+	m.save(room)
+	room.relayState()
+}
+
+// commit 保存内容变化、推送给客户端并计入保存统计，调用方需持有 room.Mu
+func (m *RealRoomManager) commit(room *RealRoom, entry *RoomOp, sender string) {
+	m.persist(room)
+	room.broadcast(entry, sender)
+	m.Metrics.recordSave()
+}
+
+// commitOp 与 commit 相同，但延迟保存：用于 WebSocket 的逐键操作，调用方需持有 room.Mu
+func (m *RealRoomManager) commitOp(room *RealRoom, entry *RoomOp, sender string) {
+	m.persistLater(room)
+	room.broadcast(entry, sender)
+	m.Metrics.recordSave()
+}
+
+// persistLater 在 roomPersistDelay 后保存房间，期间的修改合并为一次写入，调用方需持有 room.Mu。
+// 每次按键都会产生操作，逐个写入包含历史版本的完整快照代价太高；其他实例通过随事件发布的操作保持同步，
+// 进程在延迟内退出时最后几个操作不会落盘
+func (m *RealRoomManager) persistLater(room *RealRoom) {
+	if room.persistTimer != nil {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(roomPersistDelay, func() {
+		room.Mu.Lock()
+		defer room.Mu.Unlock()
+		// 期间已经保存过或房间已被删除
+		if room.persistTimer != timer {
+			return
+		}
+		m.persist(room)
+	})
+	room.persistTimer = timer
+}
+
+// cancelPersist 取消尚未执行的延迟保存，调用方需持有 r.Mu
+func (r *RealRoom) cancelPersist() {
+	if r.persistTimer != nil {
+		r.persistTimer.Stop()
+		r.persistTimer = nil
+	}
+}
+
+// save 将房间当前状态写入 store，调用方需持有 room.Mu
+func (m *RealRoomManager) save(room *RealRoom) {
+	if err := m.Store.Save(room.snapshot()); err != nil {
+		log.Printf("保存剪贴板房间 %s 失败: %v", room.ID, err)
+	}
+}
+
+func (m *RealRoomManager) deleteStored(id string) {
+	if err := m.Store.Delete(id); err != nil {
+		log.Printf("删除剪贴板房间 %s 失败: %v", id, err)
+	}
+}
+
+// FindRoom 返回已存在的房间并更新其活动时间，房间不存在或已到期时返回 nil
+func (m *RealRoomManager) FindRoom(id string) *RealRoom {
+	room := m.loadRoom(id)
+	if room == nil {
+		return nil
+	}
+
+	room.Mu.Lock()
+	// 已到期但尚未被清理的房间视为不存在
+	if room.fixedExpired(time.Now()) {
+		room.Mu.Unlock()
+		m.RemoveRoom(room)
+		m.Metrics.Expired.Add(1)
+		return nil
+	}
+	room.LastActive = time.Now()
+	if err := m.Store.Touch(id, room.LastActive); err != nil {
+		log.Printf("更新剪贴板房间 %s 失败: %v", id, err)
+	}
+	room.Mu.Unlock()
+	return room
+}
+
+// loadRoom 返回内存中的房间，不在内存中时从 store 重新加载被淘汰的房间，不更新活动时间
+func (m *RealRoomManager) loadRoom(id string) *RealRoom {
 	m.Mu.RLock()
 	room, ok := m.Rooms[id]
 	m.Mu.RUnlock()
-
 	if ok {
-		room.Mu.Lock()
-		room.LastActive = time.Now()
-		room.Mu.Unlock()
 		return room
 	}
 
 	m.Mu.Lock()
 	defer m.Mu.Unlock()
-	
-	if room, ok = m.Rooms[id]; ok {
+	return m.loadRoomLocked(id)
+}
+
+// loadRoomLocked 与 loadRoom 相同，调用方需持有 m.Mu
+func (m *RealRoomManager) loadRoomLocked(id string) *RealRoom {
+	if room, ok := m.Rooms[id]; ok {
+		return room
+	}
+	snap, ok, err := m.Store.Get(id)
+	if err != nil {
+		log.Printf("读取剪贴板房间 %s 失败: %v", id, err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	room := roomFromSnapshot(snap)
+	if time.Now().After(room.expiresAt(m.IdleTTL)) {
+		m.deleteStored(id)
+		return nil
+	}
+	if err := m.reserveLocked(); err != nil {
+		log.Printf("无法加载剪贴板房间 %s: %v", id, err)
+		return nil
+	}
+	m.add(room)
+	return room
+}
+
+// LookupRoom 与 FindRoom 相同，但本实例没有该房间时先向其他实例查询，
+// 等到持有者发布快照或 roomLookupTimeout 之后再返回
+func (m *RealRoomManager) LookupRoom(id string) *RealRoom {
+	if room := m.FindRoom(id); room != nil || !m.shared() {
 		return room
 	}
 
-	newRoom := &RealRoom{
-		ID:         id,
-		LastActive: time.Now(),
-		Clients:    make(map[chan string]bool),
+	m.Mu.Lock()
+	wait, ok := m.wants[id]
+	if !ok {
+		wait = make(chan struct{})
+		m.wants[id] = wait
+		m.relay(RoomMessage{Room: id, Want: true})
+	}
+	m.Mu.Unlock()
+
+	timer := time.NewTimer(roomLookupTimeout)
+	select {
+	case <-wait:
+	case <-timer.C:
+	}
+	timer.Stop()
+
+	m.Mu.Lock()
+	if m.wants[id] == wait {
+		delete(m.wants, id)
+	}
+	m.Mu.Unlock()
+	return m.FindRoom(id)
+}
+
+// GetRoom 返回房间，不存在时创建空房间；房间数已达上限且无法淘汰时返回 errTooManyRooms。
+// 调用方应先通过 LookupRoom 确认其他实例上没有该房间，空房间在第一次保存时才发布
+func (m *RealRoomManager) GetRoom(id string) (*RealRoom, error) {
+	if room := m.FindRoom(id); room != nil {
+		return room, nil
+	}
+
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	if room, ok := m.Rooms[id]; ok {
+		return room, nil
+	}
+	if err := m.reserveLocked(); err != nil {
+		return nil, err
+	}
+
+	newRoom := newRealRoom(id, RoomOptions{})
+	m.add(newRoom)
+	m.Metrics.RoomsCreated.Add(1)
+	return newRoom, nil
+}
+
+// CreateRoom 使用 newID 生成一个未被占用的 ID 并创建房间，检查与插入在同一把锁内完成
+func (m *RealRoomManager) CreateRoom(newID func() string, opts RoomOptions) (*RealRoom, error) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	if err := m.reserveLocked(); err != nil {
+		return nil, err
+	}
+	for i := 0; i < idMaxAttempts; i++ {
+		id := newID()
+		if _, ok := m.Rooms[id]; ok {
+			continue
+		}
+
+		room := newRealRoom(id, opts)
+		m.add(room)
+		m.Metrics.RoomsCreated.Add(1)
+		room.Mu.Lock()
+		m.persist(room)
+		room.Mu.Unlock()
+		return room, nil
+	}
+	return nil, errIDSpaceExhausted
+}
+
+// ClipboardConfig 是剪贴板工具的可配置项
+type ClipboardConfig struct {
+	// IDLength 是随机格式房间 ID 的字符数
+	IDLength int
+	// IDFormat 是默认的房间 ID 格式，创建房间时可通过 id_format 参数覆盖
+	IDFormat string
+	// MaxFileSize 是单个附件的最大字节数，RoomQuota 是每个房间附件的总字节数上限
+	MaxFileSize int64
+	RoomQuota   int64
+	// MaxContentSize 是房间内容的最大字节数
+	MaxContentSize int
+	// SavesPerMinute 和 RoomsPerMinute 是每个 IP 每分钟的保存次数和新建房间数上限
+	SavesPerMinute int
+	RoomsPerMinute int
+	// MaxRooms 是本实例内存中的房间数上限，达到上限时淘汰最久未使用的房间
+	MaxRooms int
+	// AdminToken 是访问管理和指标接口的令牌，为空时这些接口不可用
+	AdminToken string
+	// IdleTTL 是未指定有效期的房间的空闲超时，MaxTTL 是创建时可选择的最长有效期
+	IdleTTL       time.Duration
+	MaxTTL        time.Duration
+	CleanInterval time.Duration
+}
+
+// DefaultClipboardConfig 返回剪贴板的默认配置
+func DefaultClipboardConfig() ClipboardConfig {
+	return ClipboardConfig{
+		IDLength:       DefaultIDLength,
+		IDFormat:       IDFormatRandom,
+		MaxFileSize:    DefaultMaxFileSize,
+		RoomQuota:      DefaultRoomQuota,
+		MaxContentSize: DefaultMaxContentSize,
+		SavesPerMinute: DefaultSavesPerMinute,
+		RoomsPerMinute: DefaultRoomsPerMinute,
+		MaxRooms:       DefaultMaxRooms,
+		IdleTTL:        DefaultIdleTTL,
+		MaxTTL:         DefaultMaxTTL,
+		CleanInterval:  DefaultCleanInterval,
 	}
-	m.Rooms[id] = newRoom
-	return newRoom
 }
 
 type ClipboardHandler struct {
 	Render  *render.Helper
 	Manager *RealRoomManager
+	Config  ClipboardConfig
+	// saveLimit 和 createLimit 按 IP 限制保存和新建房间的频率，passwordLimit 限制房间密码的尝试次数
+	saveLimit     *rateLimiter
+	createLimit   *rateLimiter
+	passwordLimit *rateLimiter
 }
 
-func NewClipboardHandler(r *render.Helper) *ClipboardHandler {
+func NewClipboardHandler(r *render.Helper, store RoomStore, files *AttachmentStore, bus RoomBroadcaster, cfg ClipboardConfig) *ClipboardHandler {
+	defaults := DefaultClipboardConfig()
+	if cfg.IDLength < MinIDLength || cfg.IDLength > MaxIDLength {
+		cfg.IDLength = defaults.IDLength
+	}
+	if cfg.IDFormat != IDFormatWords {
+		cfg.IDFormat = defaults.IDFormat
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = defaults.MaxFileSize
+	}
+	if cfg.RoomQuota <= 0 {
+		cfg.RoomQuota = defaults.RoomQuota
+	}
+	if cfg.MaxContentSize <= 0 {
+		cfg.MaxContentSize = defaults.MaxContentSize
+	}
+	if cfg.SavesPerMinute <= 0 {
+		cfg.SavesPerMinute = defaults.SavesPerMinute
+	}
+	if cfg.RoomsPerMinute <= 0 {
+		cfg.RoomsPerMinute = defaults.RoomsPerMinute
+	}
+	if cfg.MaxRooms <= 0 {
+		cfg.MaxRooms = defaults.MaxRooms
+	}
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = defaults.IdleTTL
+	}
+	if cfg.MaxTTL < MinRoomTTL {
+		cfg.MaxTTL = defaults.MaxTTL
+	}
+	if cfg.CleanInterval <= 0 {
+		cfg.CleanInterval = defaults.CleanInterval
+	}
+
 	return &ClipboardHandler{
-		Render:  r,
-		Manager: NewRealRoomManager(),
+		Render:        r,
+		Manager:       NewRealRoomManager(store, files, bus, cfg.IdleTTL, cfg.CleanInterval, cfg.MaxRooms),
+		Config:        cfg,
+		saveLimit:     newRateLimiter(cfg.SavesPerMinute),
+		createLimit:   newRateLimiter(cfg.RoomsPerMinute),
+		passwordLimit: newRateLimiter(PasswordAttemptsPerMinute),
 	}
 }
 
-func (h *ClipboardHandler) GenerateID() string {
-	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
-	b := make([]byte, 4)
-	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+// GenerateID 按指定格式生成房间 ID，format 为空时使用配置的默认格式
+func (h *ClipboardHandler) GenerateID(format string) string {
+	if format == "" {
+		format = h.Config.IDFormat
 	}
-	return string(b)
+	return newRoomID(format, h.Config.IDLength)
 }
 
 func (h *ClipboardHandler) HandleIndex(c *gin.Context) {
@@ -99,10 +637,18 @@ func (h *ClipboardHandler) HandleIndex(c *gin.Context) {
 		lang = "en"
 	}
 
+	expiryOptions := []map[string]string{}
+	for _, choice := range roomTTLChoices {
+		if choice.TTL <= h.Config.MaxTTL {
+			expiryOptions = append(expiryOptions, map[string]string{"value": choice.Value, "label": choice.LabelKey})
+		}
+	}
+
 	h.Render.HTML(c, http.StatusOK, "clipboard_index.html", gin.H{
-		"title":       "tool_clipboard_title",
-		"description": "tool_clipboard_desc",
-		"keywords":    "tool_clipboard_keywords",
+		"ExpiryOptions": expiryOptions,
+		"title":         "tool_clipboard_title",
+		"description":   "tool_clipboard_desc",
+		"keywords":      "tool_clipboard_keywords",
 		"content_blocks": []map[string]string{
 			{
 				"title":     h.Render.Translate(lang, "clipboard_seo_h2_what"),
@@ -128,14 +674,94 @@ func (h *ClipboardHandler) HandleIndex(c *gin.Context) {
 	})
 }
 
+// HandleCreate 创建房间：GET 通过 ?mode= 选择模式，POST 表单可额外设置房间密码、有效期和阅后即焚
 func (h *ClipboardHandler) HandleCreate(c *gin.Context) {
-	id := h.GenerateID()
+	prefix := langPrefix(c)
+
+	ttl, err := parseRoomTTL(c.DefaultPostForm("expires", c.Query("expires")), h.Config.MaxTTL)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid expiry")
+		return
+	}
+	opts := RoomOptions{
+		Encrypted:     c.DefaultPostForm("mode", c.Query("mode")) == "encrypted",
+		TTL:           ttl,
+		BurnAfterRead: c.DefaultPostForm("burn", c.Query("burn")) == "1",
+	}
+	// 先检查频率再计算密码哈希，被限流的请求不消耗 bcrypt
+	if ok, wait := h.createLimit.allow(clientKey(c)); !ok {
+		retryAfter(c, wait)
+		c.String(http.StatusTooManyRequests, "too many rooms created, try again later")
+		return
+	}
+
+	if password := c.PostForm("password"); password != "" {
+		if len(password) > MaxRoomPasswordLength {
+			c.String(http.StatusBadRequest, "password too long")
+			return
+		}
+		hash, err := hashRoomPassword(password)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "failed to create room")
+			return
+		}
+		opts.PasswordHash = hash
+	}
+
+	format := c.DefaultPostForm("id_format", c.Query("id_format"))
+	room, err := h.Manager.CreateRoom(func() string { return h.GenerateID(format) }, opts)
+	if err != nil {
+		log.Printf("创建剪贴板房间失败: %v", err)
+		c.String(http.StatusServiceUnavailable, "failed to create room")
+		return
+	}
+	if room.Protected() {
+		h.setRoomCookie(c, room.ID, room.EditToken)
+	}
+	if room.BurnAfterRead {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(roomCreatorCookiePrefix+room.ID, room.CreatorToken, roomCookieMaxAge, "/", "", c.Request.TLS != nil, true)
+	}
+
+	target := prefix + "/clipboard/" + room.ID
+	// 加密房间的密钥由创建者浏览器生成，#new 片段通知页面生成密钥
+	if opts.Encrypted {
+		target += "#new"
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// langPrefix 返回当前语言的路径前缀，英文没有前缀
+func langPrefix(c *gin.Context) string {
 	lang := c.GetString("lang")
-	prefix := ""
-	if lang != "en" && lang != "" {
-		prefix = "/" + lang
+	if lang == "en" || lang == "" {
+		return ""
+	}
+	return "/" + lang
+}
+
+// HandleQRCode 生成房间链接的二维码图片，参数与 /api/qr 相同。
+// 加密房间的密钥只存在于链接的 # 片段中，服务器无从得知，这类房间的二维码由浏览器生成
+func (h *ClipboardHandler) HandleQRCode(c *gin.Context) {
+	id := c.Param("id")
+	opts, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	room := h.findRoom(c, id)
+	if room == nil {
+		return
+	}
+	domain := h.Render.Domain
+	if domain == "" {
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		domain = scheme + "://" + c.Request.Host
 	}
-	c.Redirect(http.StatusFound, prefix+"/clipboard/"+id)
+	writeQRCode(c, domain+langPrefix(c)+"/clipboard/"+room.ID, opts)
 }
 
 func (h *ClipboardHandler) HandleRoom(c *gin.Context) {
@@ -145,76 +771,198 @@ func (h *ClipboardHandler) HandleRoom(c *gin.Context) {
 		return
 	}
 
-	room := h.Manager.GetRoom(id)
+	room, err := h.openRoom(c, id)
+	if err != nil {
+		c.String(errorStatus(err), err.Error())
+		return
+	}
+	access := h.resolveAccess(c, room)
+
+	// 通过只读分享链接进入时记住令牌，后续 API 请求自动携带
+	if token := c.Query(roomTokenQuery); access == accessView && tokenEqual(token, room.ViewToken) {
+		h.setRoomCookie(c, id, token)
+	}
+
+	// 阅后即焚房间的内容不嵌入页面，由访问者主动点击读取
+	hidden := h.hidesContent(c, room)
+	content := ""
 	room.Mu.Lock()
-	content := room.Content
+	if access > accessNone && !hidden {
+		content = room.Content
+	}
+	revision, format := room.Revision, room.Format
+	expiresAt := room.expiresAt(h.Manager.IdleTTL)
+	fixedExpiry := !room.ExpiresAt.IsZero()
 	room.Mu.Unlock()
 
+	viewToken := ""
+	if access == accessEdit && room.Protected() {
+		viewToken = room.ViewToken
+	}
+
 	h.Render.HTML(c, http.StatusOK, "clipboard_room.html", gin.H{
-		"title":       "tool_clipboard_room_title",
-		"description": "tool_clipboard_room_desc",
-		"RoomID":      id,
+		"title":          "tool_clipboard_room_title",
+		"description":    "tool_clipboard_room_desc",
+		"RoomID":         id,
 		"InitialContent": content,
+		"Revision":       revision,
+		"ContentType":    format.Type,
+		"Language":       format.Language,
+		"Encrypted":      room.Encrypted,
+		"Protected":      room.Protected(),
+		"Access":         access.String(),
+		"ViewToken":      viewToken,
+		"BurnAfterRead":  room.BurnAfterRead,
+		"BurnHidden":     hidden,
+		"ExpiresAt":      expiresAt.UnixMilli(),
+		"FixedExpiry":    fixedExpiry,
+		"IdleMinutes":    int(h.Manager.IdleTTL / time.Minute),
+		"MaxContentSize": h.Config.MaxContentSize,
 	})
 }
 
 func (h *ClipboardHandler) HandleGet(c *gin.Context) {
 	id := c.Param("id")
-	room := h.Manager.GetRoom(id)
+	room := h.findRoom(c, id)
+	if room == nil || !h.requireAccess(c, room, accessView) {
+		return
+	}
+
+	// 阅后即焚：创建者以外的人第一次读到内容后立即清空并删除房间，
+	// 清空在同一把锁内完成，保证并发读取时只有一个请求能拿到内容
 	room.Mu.Lock()
-	content := room.Content
+	content, rev, format := room.Content, room.Revision, room.Format
+	burned := content != "" && h.hidesContent(c, room)
+	if burned {
+		room.Content = ""
+	}
 	room.Mu.Unlock()
 
+	if burned {
+		h.Manager.RemoveRoom(room)
+		h.Manager.Metrics.Burned.Add(1)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"content": content,
+		"status":    "success",
+		"content":   content,
+		"format":    format,
+		"encrypted": room.Encrypted,
+		"burned":    burned,
+		"rev":       rev,
 	})
 }
 
+// HandleSave 整体保存房间内容；请求带 rev 时仅在房间仍处于该修订号时保存，否则返回 409 和当前内容。
+// 请求带 content_type 时同时修改内容类型。内容超过大小上限时返回 413，保存过于频繁时返回 429
 func (h *ClipboardHandler) HandleSave(c *gin.Context) {
 	id := c.Param("id")
 	var data struct {
-		Content string `json:"content"`
+		Content     string `json:"content"`
+		ContentType string `json:"content_type"`
+		Language    string `json:"language"`
+		Author      string `json:"author"`
+		Rev         *int64 `json:"rev"`
 	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(h.maxRequestSize()))
 	if err := c.ShouldBindJSON(&data); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			abortWithError(c, errContentTooLarge)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data"})
 		return
 	}
 
-	room := h.Manager.GetRoom(id)
+	room, err := h.openRoom(c, id)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if !h.requireAccess(c, room, accessEdit) || !h.allowSave(c) {
+		return
+	}
+
+	// 加密房间只接受合法的密文信封，服务器不解析其中内容
+	if err := h.validateContent(room, data.Content); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
 	room.Mu.Lock()
-	room.Content = data.Content
-	room.LastActive = time.Now()
-	for clientChan := range room.Clients {
-		select {
-		case clientChan <- data.Content:
-		default:
-		}
+	if data.Rev != nil && *data.Rev != room.Revision {
+		conflict := room.contentData()
+		room.Mu.Unlock()
+		conflict["error"] = "conflict"
+		c.JSON(http.StatusConflict, conflict)
+		return
 	}
+	format, err := room.resolveFormat(data.ContentType, data.Language, data.Content)
+	if err != nil {
+		room.Mu.Unlock()
+		abortWithError(c, err)
+		return
+	}
+	if entry := room.setContent(data.Content, format, authorHint(c, data.Author), editSession(c)); entry != nil {
+		h.Manager.commit(room, entry, "")
+	}
+	room.LastActive = time.Now()
+	rev := room.Revision
 	room.Mu.Unlock()
 
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	c.JSON(http.StatusOK, gin.H{"status": "success", "rev": rev})
 }
 
+// HandleStream 通过 SSE 推送房间事件
+//
+// 内容事件以修订号作为事件 ID。浏览器断线重连时在 Last-Event-ID 头中带上最后收到的修订号，
+// 没有遗漏时不再重复发送内容，否则补发最新内容（内容事件携带完整内容，最新修订已包含之前的所有修改）
 func (h *ClipboardHandler) HandleStream(c *gin.Context) {
 	id := c.Param("id")
-	room := h.Manager.GetRoom(id)
+	room, err := h.openRoom(c, id)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if !h.requireAccess(c, room, accessView) {
+		return
+	}
+
+	clientChan := make(chan RoomEvent, sseEventBuffer)
+
+	// 阅后即焚房间不向非创建者推送内容
+	hidden := h.hidesContent(c, room)
+
+	client := newRoomClient(c, "sse")
 
-	clientChan := make(chan string, 10)
-	
 	room.Mu.Lock()
-	room.Clients[clientChan] = true
-	initialContent := room.Content
+	room.join(clientChan, client)
+	initial, rev := room.contentData(), room.Revision
 	room.Mu.Unlock()
 
 	defer func() {
 		room.Mu.Lock()
-		delete(room.Clients, clientChan)
-		close(clientChan)
+		room.leave(clientChan)
 		room.Mu.Unlock()
 	}()
 
-	c.SSEvent("message", gin.H{"content": initialContent})
+	send := func(ev RoomEvent) {
+		event := sse.Event{Event: ev.Name, Data: ev.Data}
+		if ev.Name == "message" {
+			event.Id = strconv.FormatInt(messageRev(ev), 10)
+		}
+		c.Render(-1, event)
+	}
+
+	// 先告诉客户端断线后的重连间隔，命令行客户端据 encrypted 判断是否需要密钥
+	c.Render(-1, sse.Event{Event: "hello", Retry: sseRetryMillis, Data: gin.H{"id": client.ID, "encrypted": room.Encrypted}})
+	switch {
+	case hidden:
+		c.SSEvent("message", gin.H{"content": "", "rev": rev})
+	case c.GetHeader("Last-Event-ID") != strconv.FormatInt(rev, 10):
+		send(RoomEvent{Name: "message", Data: initial})
+	}
 	c.Writer.Flush()
 
 	ticker := time.NewTicker(30 * time.Second)
@@ -222,8 +970,25 @@ func (h *ClipboardHandler) HandleStream(c *gin.Context) {
 
 	for {
 		select {
-		case content := <-clientChan:
-			c.SSEvent("message", gin.H{"content": content})
+		case ev := <-clientChan:
+			if !hidden || ev.Name != "message" {
+				send(ev)
+			}
+			if ev.Name == "expired" {
+				c.Writer.Flush()
+				return
+			}
+			// 缓冲区曾经溢出，补发当前状态
+			if client.lagged.Swap(false) {
+				room.Mu.Lock()
+				events := room.resyncEvents()
+				room.Mu.Unlock()
+				for _, ev := range events {
+					if !hidden || ev.Name != "message" {
+						send(ev)
+					}
+				}
+			}
 			c.Writer.Flush()
 		case <-c.Request.Context().Done():
 			return
@@ -0,0 +1,261 @@
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminTopRooms 是统计接口列出的最大房间数
+const adminTopRooms = 20
+
+// ClipboardMetrics 是剪贴板的累计计数器，进程重启后清零
+type ClipboardMetrics struct {
+	Saves        atomic.Int64
+	RoomsCreated atomic.Int64
+	// 房间删除的原因：固定有效期到期、空闲超时、房间数达到上限被淘汰、阅后即焚、管理员删除
+	Expired     atomic.Int64
+	IdleExpired atomic.Int64
+	Evicted     atomic.Int64
+	Burned      atomic.Int64
+	Deleted     atomic.Int64
+	saveRate    rateCounter
+}
+
+// recordSave 记录一次内容保存
+func (m *ClipboardMetrics) recordSave() {
+	m.Saves.Add(1)
+	m.saveRate.add(time.Now())
+}
+
+// rateCounter 按秒统计最近一分钟内的事件数
+type rateCounter struct {
+	counts [60]int64
+	// stamps 是每个槽位对应的 Unix 秒，槽位过期后重新计数
+	stamps [60]int64
+	mu     sync.Mutex
+}
+
+func (r *rateCounter) add(now time.Time) {
+	sec := now.Unix()
+	i := sec % 60
+	r.mu.Lock()
+	if r.stamps[i] != sec {
+		r.stamps[i] = sec
+		r.counts[i] = 0
+	}
+	r.counts[i]++
+	r.mu.Unlock()
+}
+
+// perSecond 返回最近一分钟的平均每秒事件数
+func (r *rateCounter) perSecond(now time.Time) float64 {
+	sec := now.Unix()
+	var total int64
+	r.mu.Lock()
+	for i, stamp := range r.stamps {
+		if sec-stamp < 60 {
+			total += r.counts[i]
+		}
+	}
+	r.mu.Unlock()
+	return float64(total) / 60
+}
+
+// RoomUsage 是单个房间的资源占用
+type RoomUsage struct {
+	ID              string    `json:"id"`
+	ContentBytes    int       `json:"content_bytes"`
+	HistoryBytes    int       `json:"history_bytes"`
+	AttachmentBytes int64     `json:"attachment_bytes"`
+	Revision        int64     `json:"revision"`
+	Clients         int       `json:"clients"`
+	Encrypted       bool      `json:"encrypted"`
+	Protected       bool      `json:"protected"`
+	LastActive      time.Time `json:"last_active"`
+}
+
+func (u RoomUsage) total() int64 {
+	return int64(u.ContentBytes+u.HistoryBytes) + u.AttachmentBytes
+}
+
+// ClipboardStats 是某一时刻的房间统计
+type ClipboardStats struct {
+	Rooms            int     `json:"rooms"`
+	MaxRooms         int     `json:"max_rooms"`
+	EncryptedRooms   int     `json:"encrypted_rooms"`
+	ProtectedRooms   int     `json:"protected_rooms"`
+	SSEClients       int     `json:"sse_clients"`
+	WebSocketClients int     `json:"websocket_clients"`
+	ContentBytes     int64   `json:"content_bytes"`
+	HistoryBytes     int64   `json:"history_bytes"`
+	AttachmentBytes  int64   `json:"attachment_bytes"`
+	SavesPerSecond   float64 `json:"saves_per_second"`
+	// TopRooms 是占用空间最多的房间
+	TopRooms []RoomUsage `json:"top_rooms"`
+}
+
+// Stats 遍历所有房间统计当前的连接数和内容大小
+func (m *RealRoomManager) Stats() ClipboardStats {
+	stats := ClipboardStats{MaxRooms: m.MaxRooms, SavesPerSecond: m.Metrics.saveRate.perSecond(time.Now())}
+	var usage []RoomUsage
+
+	m.Mu.RLock()
+	stats.Rooms = len(m.Rooms)
+	for _, room := range m.Rooms {
+		room.Mu.Lock()
+		u := RoomUsage{
+			ID:              room.ID,
+			ContentBytes:    len(room.Content),
+			AttachmentBytes: room.attachmentsSize(),
+			Revision:        room.Revision,
+			Clients:         len(room.Clients),
+			Encrypted:       room.Encrypted,
+			Protected:       room.Protected(),
+			LastActive:      room.LastActive,
+		}
+		for _, rev := range room.History {
+			u.HistoryBytes += len(rev.Content)
+		}
+		for _, client := range room.Clients {
+			if client.Transport == "websocket" {
+				stats.WebSocketClients++
+			} else {
+				stats.SSEClients++
+			}
+		}
+		room.Mu.Unlock()
+
+		if u.Encrypted {
+			stats.EncryptedRooms++
+		}
+		if u.Protected {
+			stats.ProtectedRooms++
+		}
+		stats.ContentBytes += int64(u.ContentBytes)
+		stats.HistoryBytes += int64(u.HistoryBytes)
+		stats.AttachmentBytes += u.AttachmentBytes
+		usage = append(usage, u)
+	}
+	m.Mu.RUnlock()
+
+	sort.Slice(usage, func(i, j int) bool { return usage[i].total() > usage[j].total() })
+	if len(usage) > adminTopRooms {
+		usage = usage[:adminTopRooms]
+	}
+	stats.TopRooms = usage
+	return stats
+}
+
+// RequireAdmin 校验管理令牌（Authorization: Bearer <令牌>），未配置令牌时管理接口不可用
+func (h *ClipboardHandler) RequireAdmin(c *gin.Context) {
+	if h.Config.AdminToken == "" {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || !tokenEqual(token, h.Config.AdminToken) {
+		c.Header("WWW-Authenticate", `Bearer realm="clipboard admin"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Next()
+}
+
+// HandleAdminStats 返回房间统计、累计计数器和占用空间最多的房间
+func (h *ClipboardHandler) HandleAdminStats(c *gin.Context) {
+	metrics := &h.Manager.Metrics
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"stats":  h.Manager.Stats(),
+		"counters": gin.H{
+			"saves":                  metrics.Saves.Load(),
+			"rooms_created":          metrics.RoomsCreated.Load(),
+			"rooms_expired":          metrics.Expired.Load(),
+			"rooms_idle_expired":     metrics.IdleExpired.Load(),
+			"rooms_evicted":          metrics.Evicted.Load(),
+			"rooms_burned":           metrics.Burned.Load(),
+			"rooms_deleted":          metrics.Deleted.Load(),
+			"saves_rate_limited":     h.saveLimit.rejected.Load(),
+			"rooms_rate_limited":     h.createLimit.rejected.Load(),
+			"passwords_rate_limited": h.passwordLimit.rejected.Load(),
+		},
+		"limits": gin.H{
+			"max_content_size": h.Config.MaxContentSize,
+			"saves_per_minute": h.Config.SavesPerMinute,
+			"rooms_per_minute": h.Config.RoomsPerMinute,
+			"max_rooms":        h.Config.MaxRooms,
+		},
+	})
+}
+
+// HandleAdminMetrics 以 Prometheus 文本格式输出指标
+func (h *ClipboardHandler) HandleAdminMetrics(c *gin.Context) {
+	stats := h.Manager.Stats()
+	metrics := &h.Manager.Metrics
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var b strings.Builder
+	declared := make(map[string]bool)
+	metric := func(name, kind, help string, value any, labels ...string) {
+		if !declared[name] {
+			declared[name] = true
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		}
+		if len(labels) > 0 {
+			fmt.Fprintf(&b, "%s{%s} %v\n", name, strings.Join(labels, ","), value)
+		} else {
+			fmt.Fprintf(&b, "%s %v\n", name, value)
+		}
+	}
+
+	metric("c2v2_clipboard_rooms", "gauge", "Rooms held in memory.", stats.Rooms)
+	metric("c2v2_clipboard_rooms_max", "gauge", "Room limit before least recently used rooms are evicted.", stats.MaxRooms)
+	metric("c2v2_clipboard_clients", "gauge", "Connected clients.", stats.SSEClients, `transport="sse"`)
+	metric("c2v2_clipboard_clients", "gauge", "Connected clients.", stats.WebSocketClients, `transport="websocket"`)
+	metric("c2v2_clipboard_stored_bytes", "gauge", "Bytes stored in rooms.", stats.ContentBytes, `kind="content"`)
+	metric("c2v2_clipboard_stored_bytes", "gauge", "Bytes stored in rooms.", stats.HistoryBytes, `kind="history"`)
+	metric("c2v2_clipboard_stored_bytes", "gauge", "Bytes stored in rooms.", stats.AttachmentBytes, `kind="attachment"`)
+	metric("c2v2_clipboard_saves_total", "counter", "Content changes saved.", metrics.Saves.Load())
+	metric("c2v2_clipboard_rooms_created_total", "counter", "Rooms created.", metrics.RoomsCreated.Load())
+	for _, removed := range []struct {
+		reason string
+		count  int64
+	}{
+		{"expired", metrics.Expired.Load()},
+		{"idle", metrics.IdleExpired.Load()},
+		{"evicted", metrics.Evicted.Load()},
+		{"burned", metrics.Burned.Load()},
+		{"admin", metrics.Deleted.Load()},
+	} {
+		metric("c2v2_clipboard_rooms_removed_total", "counter", "Rooms removed by reason.", removed.count, `reason="`+removed.reason+`"`)
+	}
+	metric("c2v2_clipboard_rate_limited_total", "counter", "Requests rejected by rate limits.", h.saveLimit.rejected.Load(), `action="save"`)
+	metric("c2v2_clipboard_rate_limited_total", "counter", "Requests rejected by rate limits.", h.createLimit.rejected.Load(), `action="create"`)
+	metric("c2v2_clipboard_rate_limited_total", "counter", "Requests rejected by rate limits.", h.passwordLimit.rejected.Load(), `action="password"`)
+	metric("go_memstats_heap_alloc_bytes", "gauge", "Bytes of allocated heap objects.", mem.HeapAlloc)
+	metric("go_goroutines", "gauge", "Number of goroutines.", runtime.NumGoroutine())
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// HandleAdminDeleteRoom 立即删除房间（例如滥用的房间），在线客户端会收到 expired 事件
+func (h *ClipboardHandler) HandleAdminDeleteRoom(c *gin.Context) {
+	room := h.Manager.LookupRoom(c.Param("id"))
+	if room == nil {
+		abortWithError(c, errRoomNotFound)
+		return
+	}
+	h.Manager.RemoveRoom(room)
+	h.Manager.Metrics.Deleted.Add(1)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,213 @@
+package tools
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 受保护房间的访问控制：
+//   - 编辑凭证：房间密码（bcrypt 哈希存储）或由密码换取的 EditToken
+//   - 只读凭证：创建时生成的 ViewToken，通过只读分享链接 ?t=<token> 分发
+//
+// 浏览器通过 Cookie 携带令牌，命令行等客户端可以使用请求头
+const (
+	roomTokenQuery     = "t"
+	roomTokenHeader    = "X-Clipboard-Token"
+	roomPasswordHeader = "X-Clipboard-Password"
+	roomCookiePrefix   = "cb_"
+	roomCookieMaxAge   = 7 * 24 * 3600
+
+	// MaxRoomPasswordLength 是 bcrypt 能处理的最大密码字节数
+	MaxRoomPasswordLength = 72
+	// PasswordAttemptsPerMinute 是每个 IP 每分钟校验房间密码（计算 bcrypt）的次数上限
+	PasswordAttemptsPerMinute = 10
+
+	// passwordWaitKey 在请求上下文中记录密码校验被限流时需要等待的时间
+	passwordWaitKey = "clipboard_password_wait"
+)
+
+type roomAccess int
+
+const (
+	accessNone roomAccess = iota
+	accessView
+	accessEdit
+)
+
+func (a roomAccess) String() string {
+	switch a {
+	case accessEdit:
+		return "edit"
+	case accessView:
+		return "view"
+	default:
+		return "none"
+	}
+}
+
+// randomToken 生成 128 位随机令牌
+func randomToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// hashRoomPassword 计算房间密码的 bcrypt 哈希
+func hashRoomPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+func tokenEqual(a, b string) bool {
+	return a != "" && b != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Protected 表示房间是否设置了密码
+func (r *RealRoom) Protected() bool {
+	return len(r.PasswordHash) > 0
+}
+
+func (r *RealRoom) checkPassword(password string) bool {
+	return password != "" && bcrypt.CompareHashAndPassword(r.PasswordHash, []byte(password)) == nil
+}
+
+// passwordVerified 表示 password 与最近一次校验通过的密码相同，这时不必再计算 bcrypt
+func (r *RealRoom) passwordVerified(password string) bool {
+	sum := sha256.Sum256([]byte(password))
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.verifiedPassword != nil && subtle.ConstantTimeCompare(sum[:], r.verifiedPassword) == 1
+}
+
+// rememberPassword 记住校验通过的密码的 SHA-256，只保存在内存中
+func (r *RealRoom) rememberPassword(password string) {
+	sum := sha256.Sum256([]byte(password))
+	r.Mu.Lock()
+	r.verifiedPassword = sum[:]
+	r.Mu.Unlock()
+}
+
+// verifyPassword 校验房间密码。与最近通过的密码相同时直接通过，否则计入请求者的密码尝试次数，
+// 防止在线暴力破解和用 bcrypt 耗尽 CPU；被限流时返回 false 和需要等待的时间
+func (h *ClipboardHandler) verifyPassword(c *gin.Context, room *RealRoom, password string) (bool, time.Duration) {
+	if password == "" || len(password) > MaxRoomPasswordLength {
+		return false, 0
+	}
+	if room.passwordVerified(password) {
+		return true, 0
+	}
+	if ok, wait := h.passwordLimit.allow(clientKey(c)); !ok {
+		return false, wait
+	}
+	if !room.checkPassword(password) {
+		return false, 0
+	}
+	room.rememberPassword(password)
+	return true, 0
+}
+
+// resolveAccess 根据请求携带的凭证判断对房间的访问级别
+// 阅后即焚房间只有创建者可以编辑，其他人最多只读
+func (h *ClipboardHandler) resolveAccess(c *gin.Context, room *RealRoom) roomAccess {
+	access := h.credentialAccess(c, room)
+	if access == accessEdit && room.BurnAfterRead && !h.isCreator(c, room) {
+		return accessView
+	}
+	return access
+}
+
+func (h *ClipboardHandler) credentialAccess(c *gin.Context, room *RealRoom) roomAccess {
+	if !room.Protected() {
+		return accessEdit
+	}
+
+	var tokens []string
+	if cookie, err := c.Cookie(roomCookiePrefix + room.ID); err == nil {
+		tokens = append(tokens, cookie)
+	}
+	tokens = append(tokens, c.GetHeader(roomTokenHeader), c.Query(roomTokenQuery))
+
+	access := accessNone
+	for _, token := range tokens {
+		if tokenEqual(token, room.EditToken) {
+			return accessEdit
+		}
+		if tokenEqual(token, room.ViewToken) {
+			access = accessView
+		}
+	}
+
+	ok, wait := h.verifyPassword(c, room, c.GetHeader(roomPasswordHeader))
+	if ok {
+		return accessEdit
+	}
+	if wait > 0 {
+		c.Set(passwordWaitKey, wait)
+	}
+	return access
+}
+
+// requireAccess 校验访问级别，不满足时写入错误响应并返回 false
+func (h *ClipboardHandler) requireAccess(c *gin.Context, room *RealRoom, need roomAccess) bool {
+	access := h.resolveAccess(c, room)
+	if access >= need {
+		return true
+	}
+	if wait, limited := c.Get(passwordWaitKey); limited && access == accessNone {
+		retryAfter(c, wait.(time.Duration))
+		abortWithError(c, errRateLimited)
+	} else if access == accessNone {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "password required"})
+	} else {
+		c.JSON(http.StatusForbidden, gin.H{"error": "read-only access"})
+	}
+	return false
+}
+
+// setRoomCookie 在浏览器中记住房间令牌
+func (h *ClipboardHandler) setRoomCookie(c *gin.Context, id, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(roomCookiePrefix+id, token, roomCookieMaxAge, "/", "", c.Request.TLS != nil, true)
+}
+
+// HandleUnlock 校验房间密码，成功后下发编辑令牌 Cookie
+func (h *ClipboardHandler) HandleUnlock(c *gin.Context) {
+	id := c.Param("id")
+	var data struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data"})
+		return
+	}
+
+	room := h.findRoom(c, id)
+	if room == nil {
+		return
+	}
+	if !room.Protected() {
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+		return
+	}
+	ok, wait := h.verifyPassword(c, room, data.Password)
+	if wait > 0 {
+		retryAfter(c, wait)
+		abortWithError(c, errRateLimited)
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "wrong password"})
+		return
+	}
+
+	h.setRoomCookie(c, id, room.EditToken)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 房间内容的类型
+const (
+	ContentText  = "text"
+	ContentCode  = "code"
+	ContentJSON  = "json"
+	ContentImage = "image"
+)
+
+// imageMIMETypes 是图片房间允许的图片格式
+var imageMIMETypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// languagePattern 限制代码语言名称，例如 go、c++、c#、objective-c
+var languagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]{0,31}$`)
+
+var (
+	errInvalidContentType = errors.New("invalid content type")
+	errInvalidLanguage    = errors.New("invalid language")
+	errInvalidJSON        = errors.New("content is not valid JSON")
+	errInvalidImage       = errors.New("content is not a supported image data URI")
+	errImageOps           = errors.New("operations are not available in image rooms")
+)
+
+// ContentFormat 描述房间内容的类型，Language 只用于代码
+type ContentFormat struct {
+	Type     string `json:"type"`
+	Language string `json:"language,omitempty"`
+}
+
+// textFormat 是房间的默认类型
+var textFormat = ContentFormat{Type: ContentText}
+
+// orText 把未设置的类型（旧版本保存的房间和历史版本）视为纯文本
+func (f ContentFormat) orText() ContentFormat {
+	if f.Type == "" {
+		return textFormat
+	}
+	return f
+}
+
+// parseFormat 检查客户端提交的类型和语言，类型为空表示纯文本
+func parseFormat(kind, language string) (ContentFormat, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	language = strings.ToLower(strings.TrimSpace(language))
+	switch kind {
+	case "", ContentText:
+		kind = ContentText
+	case ContentCode, ContentJSON, ContentImage:
+	default:
+		return ContentFormat{}, errInvalidContentType
+	}
+	if language != "" {
+		if kind != ContentCode || !languagePattern.MatchString(language) {
+			return ContentFormat{}, errInvalidLanguage
+		}
+	}
+	return ContentFormat{Type: kind, Language: language}, nil
+}
+
+// checkFormat 检查内容是否符合类型：JSON 必须可以解析，图片必须是允许格式的 data URI。
+// 加密房间的内容是密文，由浏览器在本地检查
+func checkFormat(format ContentFormat, content string, encrypted bool) error {
+	if encrypted || content == "" {
+		return nil
+	}
+	switch format.Type {
+	case ContentJSON:
+		if !json.Valid([]byte(content)) {
+			return errInvalidJSON
+		}
+	case ContentImage:
+		return checkImageDataURI(content)
+	}
+	return nil
+}
+
+// checkImageDataURI 检查 data:image/...;base64,... 格式的图片，并确认数据确实是声明的格式
+func checkImageDataURI(content string) error {
+	header, data, ok := strings.Cut(content, ",")
+	if !ok {
+		return errInvalidImage
+	}
+	mime, ok := strings.CutPrefix(header, "data:")
+	if !ok {
+		return errInvalidImage
+	}
+	mime, ok = strings.CutSuffix(mime, ";base64")
+	if !ok || !imageMIMETypes[mime] {
+		return errInvalidImage
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(raw) == 0 {
+		return errInvalidImage
+	}
+	if http.DetectContentType(raw) != mime {
+		return errInvalidImage
+	}
+	return nil
+}
+
+// resolveFormat 返回保存 content 后房间的类型，调用方需持有 r.Mu
+// kind 为空时沿用当前类型；显式指定类型时内容必须符合该类型。
+// JSON 房间在编辑过程中允许暂时不合法，图片房间的内容始终需要是合法的图片
+func (r *RealRoom) resolveFormat(kind, language, content string) (ContentFormat, error) {
+	if kind == "" {
+		if r.Format.Type == ContentImage {
+			return r.Format, checkFormat(r.Format, content, r.Encrypted)
+		}
+		return r.Format, nil
+	}
+	format, err := parseFormat(kind, language)
+	if err != nil {
+		return format, err
+	}
+	return format, checkFormat(format, content, r.Encrypted)
+}
+
+// contentData 返回内容事件的数据，调用方需持有 r.Mu
+func (r *RealRoom) contentData() gin.H {
+	return gin.H{"content": r.Content, "rev": r.Revision, "format": r.Format}
+}
+
+// HandleSetType 修改房间内容的类型，内容不变。类型变化作为一个新修订号推送给所有客户端
+func (h *ClipboardHandler) HandleSetType(c *gin.Context) {
+	id := c.Param("id")
+	var data struct {
+		Type     string `json:"type"`
+		Language string `json:"language"`
+		Author   string `json:"author"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data"})
+		return
+	}
+
+	format, err := parseFormat(data.Type, data.Language)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	room := h.findRoom(c, id)
+	if room == nil || !h.requireAccess(c, room, accessEdit) || !h.allowSave(c) {
+		return
+	}
+
+	room.Mu.Lock()
+	if err := checkFormat(format, room.Content, room.Encrypted); err != nil {
+		room.Mu.Unlock()
+		abortWithError(c, err)
+		return
+	}
+	if format != room.Format {
+		// 内容没有变化，操作只保留原文，其他设备据 format 更新显示方式
+		entry := room.edit(room.Content, format, nil, authorHint(c, data.Author), editSession(c))
+		h.Manager.commit(room, entry, "")
+	}
+	room.LastActive = time.Now()
+	rev := room.Revision
+	room.Mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "rev": rev, "format": format})
+}
+
+// HandlePretty 返回 JSON 房间格式化后的内容，解析失败时返回 422、第一个错误所在的行和列以及全部问题列表
+func (h *ClipboardHandler) HandlePretty(c *gin.Context) {
+	id := c.Param("id")
+	room := h.findRoom(c, id)
+	if room == nil || !h.requireAccess(c, room, accessView) {
+		return
+	}
+	if room.Encrypted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "encrypted rooms are formatted in the browser"})
+		return
+	}
+	// 阅后即焚房间的内容只能通过读取接口获取一次
+	if h.hidesContent(c, room) {
+		abortWithError(c, errRoomNotFound)
+		return
+	}
+
+	room.Mu.Lock()
+	content, rev := room.Content, room.Revision
+	room.Mu.Unlock()
+
+	pretty, err := formatJSON(content)
+	if err != nil {
+		diags := diagnoseJSON(content, err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":       diags[0].Message,
+			"line":        diags[0].Line,
+			"column":      diags[0].Column,
+			"diagnostics": diags[:min(len(diags), maxJSONDiagnostics)],
+			"rev":         rev,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "content": pretty, "rev": rev})
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// 端到端加密房间的内容是浏览器用 AES-GCM 生成的密文信封：
+//
+//	v1.<base64url(iv)>.<base64url(ciphertext+tag)>
+//
+// 密钥只存在于 URL 片段 (#key=...) 中，不会发送到服务器；
+// 服务器只校验信封格式和大小，存储并转发密文，从不接触明文
+const (
+	envelopeVersion = "v1"
+	envelopeIVSize  = 12 // AES-GCM 推荐的 nonce 长度
+	envelopeTagSize = 16 // AES-GCM 认证标签长度
+
+	// MaxEnvelopeSize 是单个密文信封的最大字节数
+	MaxEnvelopeSize = 2 << 20
+)
+
+var (
+	errEnvelopeTooLarge = errors.New("encrypted content too large")
+	errEnvelopeFormat   = errors.New("invalid encrypted envelope")
+)
+
+// validateEnvelope 校验密文信封格式，空字符串表示清空内容，视为合法
+func validateEnvelope(s string) error {
+	if s == "" {
+		return nil
+	}
+	if len(s) > MaxEnvelopeSize {
+		return errEnvelopeTooLarge
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 || parts[0] != envelopeVersion {
+		return errEnvelopeFormat
+	}
+
+	iv, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(iv) != envelopeIVSize {
+		return errEnvelopeFormat
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(ciphertext) < envelopeTagSize {
+		return errEnvelopeFormat
+	}
+
+	return nil
+}
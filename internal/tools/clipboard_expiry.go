@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultIdleTTL 是未指定有效期的房间在无活动后被清理的时间
+	DefaultIdleTTL = 30 * time.Minute
+	// DefaultMaxTTL 是创建房间时可选择的最长有效期
+	DefaultMaxTTL = 7 * 24 * time.Hour
+	// DefaultCleanInterval 是清理过期房间的检查间隔
+	DefaultCleanInterval = time.Minute
+	// MinRoomTTL 是创建房间时可选择的最短有效期
+	MinRoomTTL = 10 * time.Minute
+
+	roomCreatorCookiePrefix = "cbo_"
+)
+
+var errInvalidTTL = errors.New("invalid expiry")
+
+// roomTTLChoices 是创建页面提供的有效期选项（会按服务器上限过滤）
+var roomTTLChoices = []struct {
+	Value    string
+	LabelKey string
+	TTL      time.Duration
+}{
+	{"10m", "clipboard_expiry_10m", 10 * time.Minute},
+	{"1h", "clipboard_expiry_1h", time.Hour},
+	{"24h", "clipboard_expiry_1d", 24 * time.Hour},
+	{"168h", "clipboard_expiry_7d", 7 * 24 * time.Hour},
+}
+
+// parseRoomTTL 解析创建房间时选择的有效期，空字符串表示使用空闲过期，超过上限时按上限处理
+func parseRoomTTL(s string, maxTTL time.Duration) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl < MinRoomTTL {
+		return 0, errInvalidTTL
+	}
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	return ttl, nil
+}
+
+// expiresAt 返回房间的过期时间：创建时指定了有效期的房间到期即删除，
+// 否则在最后一次活动 idleTTL 之后过期。调用方需持有 r.Mu
+func (r *RealRoom) expiresAt(idleTTL time.Duration) time.Time {
+	if !r.ExpiresAt.IsZero() {
+		return r.ExpiresAt
+	}
+	return r.LastActive.Add(idleTTL)
+}
+
+// fixedExpired 表示房间的固定有效期已到，调用方需持有 r.Mu
+func (r *RealRoom) fixedExpired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
+}
+
+// isCreator 判断请求者是否为阅后即焚房间的创建者
+func (h *ClipboardHandler) isCreator(c *gin.Context, room *RealRoom) bool {
+	if room.CreatorToken == "" {
+		return false
+	}
+	cookie, err := c.Cookie(roomCreatorCookiePrefix + room.ID)
+	return err == nil && tokenEqual(cookie, room.CreatorToken)
+}
+
+// hidesContent 表示阅后即焚房间在被读取前不向该请求者展示内容
+func (h *ClipboardHandler) hidesContent(c *gin.Context, room *RealRoom) bool {
+	return room.BurnAfterRead && !h.isCreator(c, room)
+}
+
+// RemoveRoom 立即删除房间（阅后即焚），并通知在线客户端房间已失效
+func (m *RealRoomManager) RemoveRoom(room *RealRoom) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	if m.Rooms[room.ID] != room {
+		return
+	}
+	room.Mu.Lock()
+	m.removeLocked(room)
+	room.Mu.Unlock()
+}
+
+// removeLocked 删除房间并通知其他实例，调用方需同时持有 m.Mu 和 room.Mu
+func (m *RealRoomManager) removeLocked(room *RealRoom) {
+	m.deleteLocked(room)
+	room.relay(RoomMessage{Deleted: true})
+}
+
+// unloadLocked 只把房间移出内存，存储和附件保留，尚未写入的操作先保存。调用方需同时持有 m.Mu 和 room.Mu
+func (m *RealRoomManager) unloadLocked(room *RealRoom) {
+	if room.persistTimer != nil {
+		m.persist(room)
+	}
+	delete(m.Rooms, room.ID)
+}
+
+// deleteLocked 从内存、存储和附件目录中删除房间，只用于房间真正失效（到期、阅后即焚或其他实例已删除），
+// 调用方需同时持有 m.Mu 和 room.Mu
+func (m *RealRoomManager) deleteLocked(room *RealRoom) {
+	room.cancelPersist()
+	delete(m.Rooms, room.ID)
+	m.deleteStored(room.ID)
+	if err := m.Files.DeleteRoom(room.ID); err != nil {
+		log.Printf("删除剪贴板房间 %s 的附件失败: %v", room.ID, err)
+	}
+	room.deliver(RoomEvent{Name: "expired", Data: gin.H{"id": room.ID}})
+}
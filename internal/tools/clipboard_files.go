@@ -0,0 +1,386 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultMaxFileSize 是单个附件的默认大小上限
+	DefaultMaxFileSize = 10 << 20
+	// DefaultRoomQuota 是每个房间所有附件的默认总大小上限
+	DefaultRoomQuota = 50 << 20
+
+	maxFileNameLength = 120
+	// multipart 表单头部等额外开销
+	multipartOverhead = 1 << 20
+)
+
+var (
+	errFileTooLarge    = errors.New("file too large")
+	errInvalidFilePath = errors.New("invalid attachment path")
+)
+
+// Attachment 是房间附件的元数据，文件内容保存在 AttachmentStore 中
+type Attachment struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	MIME       string    `json:"mime"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	Author     string    `json:"author,omitempty"`
+}
+
+// attachmentsSize 返回房间附件的总大小，调用方需持有 r.Mu
+func (r *RealRoom) attachmentsSize() int64 {
+	var total int64
+	for _, a := range r.Attachments {
+		total += a.Size
+	}
+	return total
+}
+
+func (r *RealRoom) findAttachment(fileID string) (Attachment, bool) {
+	for _, a := range r.Attachments {
+		if a.ID == fileID {
+			return a, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// AttachmentStore 将附件保存在 <dir>/<房间 ID>/<附件 ID> 文件中
+type AttachmentStore struct {
+	dir string
+}
+
+// NewAttachmentStore 创建附件存储，dir 为空时使用系统临时目录
+func NewAttachmentStore(dir string) (*AttachmentStore, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "c2v2-clipboard-files")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建附件目录失败: %w", err)
+	}
+	return &AttachmentStore{dir: dir}, nil
+}
+
+// safePathComponent 检查房间 ID 和附件 ID 能否安全地用作路径片段
+// 房间 ID 来自 URL，必须拒绝 ".." 等可能逃逸出附件目录的值
+func safePathComponent(s string) bool {
+	if s == "" || len(s) > 128 {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *AttachmentStore) roomDir(roomID string) (string, error) {
+	if !safePathComponent(roomID) {
+		return "", errInvalidFilePath
+	}
+	return filepath.Join(s.dir, roomID), nil
+}
+
+// Path 返回附件文件的路径
+func (s *AttachmentStore) Path(roomID, fileID string) (string, error) {
+	dir, err := s.roomDir(roomID)
+	if err != nil || !safePathComponent(fileID) {
+		return "", errInvalidFilePath
+	}
+	return filepath.Join(dir, fileID), nil
+}
+
+// Save 将 r 写入附件文件，超过 limit 字节时删除文件并返回 errFileTooLarge
+func (s *AttachmentStore) Save(roomID, fileID string, r io.Reader, limit int64) (int64, error) {
+	path, err := s.Path(roomID, fileID)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > limit {
+		err = errFileTooLarge
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *AttachmentStore) Delete(roomID, fileID string) error {
+	path, err := s.Path(roomID, fileID)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// DeleteRoom 删除房间的全部附件
+func (s *AttachmentStore) DeleteRoom(roomID string) error {
+	dir, err := s.roomDir(roomID)
+	if err != nil {
+		// 非法 ID 的房间不可能上传过附件
+		return nil
+	}
+	return os.RemoveAll(dir)
+}
+
+// Prune 删除不属于 keep 中任何房间的附件目录（例如重启前已过期的房间），只能在单实例部署时调用
+func (s *AttachmentStore) Prune(keep map[string]*RealRoom) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, ok := keep[entry.Name()]; !ok {
+			if err := os.RemoveAll(filepath.Join(s.dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sanitizeFileName 只保留文件名部分并限制长度，避免路径穿越和过长的响应头
+func sanitizeFileName(name string) string {
+	name = strings.ToValidUTF8(filepath.Base(strings.ReplaceAll(name, "\\", "/")), "")
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if utf8.RuneCountInString(name) > maxFileNameLength {
+		ext := filepath.Ext(name)
+		if utf8.RuneCountInString(ext) > 16 {
+			ext = ""
+		}
+		name = string([]rune(name)[:maxFileNameLength-utf8.RuneCountInString(ext)]) + ext
+	}
+	return name
+}
+
+// broadcastFiles 通知客户端附件列表已变化，调用方需持有 room.Mu
+func (r *RealRoom) broadcastFiles() {
+	r.broadcastEvent(r.filesEvent())
+}
+
+// filesEvent 返回包含当前附件列表的事件，调用方需持有 room.Mu
+func (r *RealRoom) filesEvent() RoomEvent {
+	files := make([]Attachment, len(r.Attachments))
+	copy(files, r.Attachments)
+	return RoomEvent{Name: "files", Data: gin.H{"files": files}}
+}
+
+// HandleUpload 接收 multipart 上传的附件（字段名 file）
+func (h *ClipboardHandler) HandleUpload(c *gin.Context) {
+	id := c.Param("id")
+	room, err := h.openRoom(c, id)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if !h.requireAccess(c, room, accessEdit) || !h.allowSave(c) {
+		return
+	}
+	// 加密房间的内容不能以明文形式到达服务器，因此不提供附件功能
+	if room.Encrypted {
+		c.JSON(http.StatusForbidden, gin.H{"error": "attachments are not available in encrypted rooms"})
+		return
+	}
+	if room.BurnAfterRead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "attachments are not available in burn-after-read rooms"})
+		return
+	}
+
+	maxSize := h.Config.MaxFileSize
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errFileTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+		return
+	}
+	if header.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": errFileTooLarge.Error()})
+		return
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file"})
+		return
+	}
+	defer src.Close()
+
+	// 先按声明类型，否则根据文件头识别 MIME 类型
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(src, sniff)
+	mime := header.Header.Get("Content-Type")
+	if mime == "" || mime == "application/octet-stream" {
+		mime = http.DetectContentType(sniff[:n])
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file"})
+		return
+	}
+
+	fileID := randomToken()
+	size, err := h.Manager.Files.Save(id, fileID, src, maxSize)
+	if err != nil {
+		if errors.Is(err, errFileTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, errInvalidFilePath) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid room id"})
+			return
+		}
+		log.Printf("保存剪贴板附件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
+		return
+	}
+
+	attachment := Attachment{
+		ID:         fileID,
+		Name:       sanitizeFileName(header.Filename),
+		Size:       size,
+		MIME:       mime,
+		UploadedAt: time.Now(),
+		Author:     authorHint(c, c.PostForm("author")),
+	}
+
+	room.Mu.Lock()
+	if room.attachmentsSize()+size > h.Config.RoomQuota {
+		room.Mu.Unlock()
+		h.Manager.Files.Delete(id, fileID)
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "room storage quota exceeded"})
+		return
+	}
+	room.Attachments = append(room.Attachments, attachment)
+	room.LastActive = time.Now()
+	h.Manager.persist(room)
+	room.broadcastFiles()
+	room.Mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "file": attachment})
+}
+
+// HandleFiles 返回房间的附件列表及配额使用情况
+func (h *ClipboardHandler) HandleFiles(c *gin.Context) {
+	id := c.Param("id")
+	room := h.findRoom(c, id)
+	if room == nil || !h.requireAccess(c, room, accessView) {
+		return
+	}
+
+	room.Mu.Lock()
+	files := make([]Attachment, len(room.Attachments))
+	copy(files, room.Attachments)
+	used := room.attachmentsSize()
+	room.Mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":        "success",
+		"files":         files,
+		"used":          used,
+		"quota":         h.Config.RoomQuota,
+		"max_file_size": h.Config.MaxFileSize,
+	})
+}
+
+// HandleDownload 以附件形式下载文件，避免浏览器内联渲染上传的 HTML 等内容
+func (h *ClipboardHandler) HandleDownload(c *gin.Context) {
+	id := c.Param("id")
+	room := h.findRoom(c, id)
+	if room == nil || !h.requireAccess(c, room, accessView) {
+		return
+	}
+
+	room.Mu.Lock()
+	attachment, ok := room.findAttachment(c.Param("file"))
+	room.Mu.Unlock()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+
+	path, err := h.Manager.Files.Path(id, attachment.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+	c.Header("Content-Type", attachment.MIME)
+	c.FileAttachment(path, attachment.Name)
+}
+
+// HandleDeleteFile 删除房间中的一个附件
+func (h *ClipboardHandler) HandleDeleteFile(c *gin.Context) {
+	id := c.Param("id")
+	room := h.findRoom(c, id)
+	if room == nil || !h.requireAccess(c, room, accessEdit) {
+		return
+	}
+
+	fileID := c.Param("file")
+	room.Mu.Lock()
+	index := -1
+	for i, a := range room.Attachments {
+		if a.ID == fileID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		room.Mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+	room.Attachments = append(room.Attachments[:index:index], room.Attachments[index+1:]...)
+	h.Manager.persist(room)
+	room.broadcastFiles()
+	room.Mu.Unlock()
+
+	if err := h.Manager.Files.Delete(id, fileID); err != nil {
+		log.Printf("删除剪贴板附件失败: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
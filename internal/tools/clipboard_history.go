@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// MaxRoomHistory 是每个房间保留的历史版本数，超出后丢弃最旧的版本
+	MaxRoomHistory = 20
+	// 同一会话在该时间窗口内的连续保存视为一次编辑，只记录一个历史版本
+	historyCoalesceWindow = 30 * time.Second
+	maxAuthorLength       = 40
+
+	// editSessionHeader 是浏览器标签页等 HTTP 客户端的会话 ID，用于合并连续保存
+	editSessionHeader = "X-Clipboard-Session"
+	maxSessionLength  = 64
+)
+
+// RoomRevision 是房间内容的一个历史版本
+type RoomRevision struct {
+	Rev     int64         `json:"rev"`
+	Content string        `json:"content"`
+	Format  ContentFormat `json:"format,omitzero"`
+	SavedAt time.Time     `json:"saved_at"`
+	Author  string        `json:"author,omitempty"`
+}
+
+// setContent 用新内容和类型整体替换房间内容，两者都未变化时返回 nil，调用方需持有 r.Mu
+func (r *RealRoom) setContent(content string, format ContentFormat, author, session string) *RoomOp {
+	if content == r.Content && format == r.Format {
+		return nil
+	}
+	return r.edit(content, format, nil, author, session)
+}
+
+// edit 更新房间内容和类型、递增修订号并把被替换的版本放入历史，调用方需持有 r.Mu
+// op 是对应的文本操作，为 nil 时根据新旧内容计算；即使内容未变化也会产生新的修订号
+// session 是提交者的会话（WebSocket 连接或浏览器标签页），同一会话的连续保存合并为一个历史版本。
+// 作者名称只是显示用的提示，不同的人可能相同，不能用来判断；session 为空时不合并
+func (r *RealRoom) edit(content string, format ContentFormat, op TextOp, author, session string) *RoomOp {
+	now := time.Now()
+	coalesce := session != "" && session == r.session && now.Sub(r.UpdatedAt) < historyCoalesceWindow
+	// 阅后即焚房间不保留历史，避免内容在读取后仍可找回
+	if r.Content != "" && content != r.Content && !coalesce && !r.BurnAfterRead {
+		r.History = append(r.History, RoomRevision{
+			Rev:     r.Revision,
+			Content: r.Content,
+			Format:  r.Format,
+			SavedAt: r.UpdatedAt,
+			Author:  r.Author,
+		})
+		if len(r.History) > MaxRoomHistory {
+			r.History = r.History[len(r.History)-MaxRoomHistory:]
+		}
+	}
+
+	r.Revision++
+	entry := &RoomOp{Rev: r.Revision}
+	if format != r.Format {
+		entry.Format = &format
+		r.Format = format
+	}
+	// 服务器无法解析加密房间的内容，不记录操作
+	if !r.Encrypted {
+		if op == nil {
+			op = diffOp(r.Content, content)
+		}
+		entry.Ops = op
+		r.logOp(entry)
+	}
+
+	r.Content = content
+	r.Author = author
+	r.session = session
+	r.UpdatedAt = now
+	r.LastActive = now
+	return entry
+}
+
+// findRevision 在历史中查找指定版本，调用方需持有 r.Mu
+func (r *RealRoom) findRevision(rev int64) (RoomRevision, bool) {
+	for _, revision := range r.History {
+		if revision.Rev == rev {
+			return revision, true
+		}
+	}
+	return RoomRevision{}, false
+}
+
+// cleanAuthor 清理客户端提供的作者名称并限制长度
+func cleanAuthor(name string) string {
+	name = strings.TrimSpace(strings.ToValidUTF8(name, ""))
+	if utf8.RuneCountInString(name) > maxAuthorLength {
+		name = string([]rune(name)[:maxAuthorLength])
+	}
+	return name
+}
+
+// editSession 返回 HTTP 请求携带的会话 ID，没有时返回空字符串
+func editSession(c *gin.Context) string {
+	session := c.GetHeader(editSessionHeader)
+	if len(session) > maxSessionLength {
+		return ""
+	}
+	return session
+}
+
+// authorHint 返回保存者的简短描述：优先使用客户端提供的名称，否则根据 User-Agent 推断
+func authorHint(c *gin.Context, explicit string) string {
+	if explicit = cleanAuthor(explicit); explicit != "" {
+		return explicit
+	}
+
+	ua := c.GetHeader("User-Agent")
+	browser := ""
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(ua, "curl/"):
+		return "curl"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		platform = "iOS"
+	case strings.Contains(ua, "Android"):
+		platform = "Android"
+	case strings.Contains(ua, "Mac OS X"):
+		platform = "macOS"
+	case strings.Contains(ua, "Windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "Linux"):
+		platform = "Linux"
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	default:
+		return platform
+	}
+}
+
+// HandleHistory 返回房间的历史版本，按从新到旧排列
+func (h *ClipboardHandler) HandleHistory(c *gin.Context) {
+	id := c.Param("id")
+	room := h.findRoom(c, id)
+	if room == nil || !h.requireAccess(c, room, accessView) {
+		return
+	}
+
+	room.Mu.Lock()
+	revisions := make([]RoomRevision, 0, len(room.History))
+	for i := len(room.History) - 1; i >= 0; i-- {
+		revisions = append(revisions, room.History[i])
+	}
+	current := room.Revision
+	room.Mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"revision":  current,
+		"revisions": revisions,
+		"encrypted": room.Encrypted,
+	})
+}
+
+// HandleRestore 将房间内容恢复为指定历史版本并广播给所有客户端
+func (h *ClipboardHandler) HandleRestore(c *gin.Context) {
+	id := c.Param("id")
+	var data struct {
+		Rev    int64  `json:"rev"`
+		Author string `json:"author"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data"})
+		return
+	}
+
+	room := h.findRoom(c, id)
+	if room == nil || !h.requireAccess(c, room, accessEdit) || !h.allowSave(c) {
+		return
+	}
+
+	room.Mu.Lock()
+	revision, ok := room.findRevision(data.Rev)
+	if !ok {
+		room.Mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+		return
+	}
+	format := revision.Format.orText()
+	if entry := room.setContent(revision.Content, format, authorHint(c, data.Author), ""); entry != nil {
+		h.Manager.commit(room, entry, "")
+	}
+	current := room.Revision
+	room.Mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "content": revision.Content, "format": format, "rev": current})
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// 房间 ID 格式
+const (
+	IDFormatRandom = "random" // 小写字母和数字组成的随机串，如 k3x9q2mf
+	IDFormatWords  = "words"  // 便于口头传达的单词组合，如 lamp-river-oak-tide
+
+	DefaultIDLength = 8
+	MinIDLength     = 4
+	MaxIDLength     = 32
+
+	idCharset     = "abcdefghijklmnopqrstuvwxyz0123456789"
+	idWordCount   = 4 // 4 个单词约 32 位熵
+	idMaxAttempts = 16
+)
+
+var errIDSpaceExhausted = errors.New("failed to allocate unique room id")
+
+// idWords 是单词格式 ID 使用的 256 个短单词，均为四个字母以内、发音清晰的常用词
+var idWords = [256]string{
+	"able", "area", "away", "baby", "ball", "band", "bank", "base", "bath", "bear", "bell", "bird", "body", "bone", "boot", "bowl",
+	"burn", "bush", "cake", "calm", "camp", "card", "care", "cart", "case", "cast", "cell", "chat", "chip", "city", "clay", "club",
+	"coal", "coat", "code", "cold", "cook", "cool", "copy", "core", "corn", "crew", "crop", "dark", "data", "date", "dawn", "deep",
+	"deer", "desk", "dial", "dock", "door", "dove", "down", "draw", "drop", "duck", "dust", "duty", "earn", "east", "edge", "face",
+	"fact", "fair", "farm", "fast", "fern", "file", "film", "fire", "firm", "fish", "flag", "flat", "flow", "foam", "fold", "folk",
+	"food", "foot", "fork", "form", "fort", "fuel", "full", "gain", "game", "gate", "gear", "gift", "glad", "glow", "goal", "gold",
+	"golf", "good", "grid", "grow", "gulf", "hair", "half", "hall", "hand", "hard", "harp", "hawk", "head", "hero", "hill", "hint",
+	"home", "hook", "hope", "horn", "hour", "idea", "inch", "iron", "item", "jazz", "joke", "jump", "keen", "kind", "king", "knee",
+	"lake", "lamp", "land", "lane", "leaf", "lens", "life", "lift", "lime", "line", "lion", "list", "loaf", "lock", "loft", "long",
+	"loop", "luck", "mail", "main", "mark", "mask", "mast", "meal", "mild", "milk", "mill", "mint", "mist", "mode", "moon", "moss",
+	"nail", "name", "navy", "neck", "nest", "news", "nine", "node", "nose", "note", "oak", "open", "oval", "oven", "pace", "page",
+	"palm", "park", "path", "pear", "pine", "pink", "pipe", "plan", "plot", "plum", "poem", "pool", "port", "pure", "quiz", "race",
+	"rain", "ramp", "reef", "rice", "ring", "road", "rock", "roof", "room", "root", "rope", "rose", "ruby", "rule", "safe", "sage",
+	"salt", "sand", "seal", "ship", "shoe", "silk", "site", "soap", "sock", "sofa", "soil", "song", "spin", "star", "stem", "step",
+	"swan", "tail", "tank", "taxi", "team", "tide", "time", "tone", "tool", "tour", "town", "tree", "trip", "tube", "tune", "vase",
+	"vine", "wave", "week", "well", "west", "wind", "wing", "wire", "wolf", "wood", "wool", "yard", "yarn", "zero", "zinc", "zone",
+}
+
+// randomIndex 使用 crypto/rand 返回 [0, n) 内均匀分布的随机数
+func randomIndex(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err)
+	}
+	return int(v.Int64())
+}
+
+// newRoomID 按指定格式生成房间 ID
+func newRoomID(format string, length int) string {
+	if format == IDFormatWords {
+		parts := make([]string, idWordCount)
+		for i := range parts {
+			parts[i] = idWords[randomIndex(len(idWords))]
+		}
+		return strings.Join(parts, "-")
+	}
+
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = idCharset[randomIndex(len(idCharset))]
+	}
+	return string(b)
+}
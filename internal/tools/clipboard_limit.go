@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultMaxContentSize 是房间内容（加密房间为密文信封）的默认最大字节数
+	DefaultMaxContentSize = 1 << 20
+	// DefaultSavesPerMinute 是每个 IP 每分钟的默认保存次数上限
+	DefaultSavesPerMinute = 120
+	// DefaultRoomsPerMinute 是每个 IP 每分钟的默认新建房间数上限
+	DefaultRoomsPerMinute = 10
+	// DefaultMaxRooms 是本实例内存中的默认房间数上限
+	DefaultMaxRooms = 10000
+
+	// 保存请求的 JSON 转义可能使内容变长，请求体上限在内容上限之外留出余量
+	requestBodyOverhead = 64 << 10
+)
+
+var (
+	errContentTooLarge = errors.New("content too large")
+	errRateLimited     = errors.New("too many requests")
+	errTooManyRooms    = errors.New("too many rooms")
+	errRoomNotFound    = errors.New("room not found")
+)
+
+// errorStatus 返回错误对应的 HTTP 状态码
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errContentTooLarge), errors.Is(err, errEnvelopeTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errTooManyRooms):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, errRoomNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// rateLimiter 按客户端限制操作频率（令牌桶）：每分钟补充 perMinute 次，最多积累 perMinute 次
+type rateLimiter struct {
+	perMinute int
+	buckets   map[string]*tokenBucket
+	swept     time.Time
+	mu        sync.Mutex
+	// rejected 是被拒绝的累计次数
+	rejected atomic.Int64
+}
+
+type tokenBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		perMinute: perMinute,
+		buckets:   make(map[string]*tokenBucket),
+		swept:     time.Now(),
+	}
+}
+
+// allow 消耗 key 的一次配额，配额用完时返回 false 和需要等待的时间
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	now := time.Now()
+	limit := float64(l.perMinute)
+	perSecond := limit / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: limit, at: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(limit, b.tokens+now.Sub(b.at).Seconds()*perSecond)
+	b.at = now
+	if b.tokens < 1 {
+		l.rejected.Add(1)
+		return false, time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep 每分钟删除一次已经回满的令牌桶，调用方需持有 l.mu
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if now.Sub(b.at) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientKey 返回限流使用的客户端标识，IPv6 地址按 /64 网段计算，避免轮换地址绕过限制
+func clientKey(c *gin.Context) string {
+	ip := net.ParseIP(c.ClientIP())
+	if ip == nil {
+		return c.ClientIP()
+	}
+	if ip.To4() == nil {
+		return ip.Mask(net.CIDRMask(64, 128)).String()
+	}
+	return ip.String()
+}
+
+// retryAfter 设置 Retry-After 响应头并返回等待的秒数
+func retryAfter(c *gin.Context, wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	return seconds
+}
+
+// abortWithError 按错误类型返回 JSON 错误响应
+func abortWithError(c *gin.Context, err error) {
+	c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+}
+
+// allowSave 检查请求者的保存频率，超出时返回 429
+func (h *ClipboardHandler) allowSave(c *gin.Context) bool {
+	ok, wait := h.saveLimit.allow(clientKey(c))
+	if !ok {
+		retryAfter(c, wait)
+		abortWithError(c, errRateLimited)
+	}
+	return ok
+}
+
+// openRoom 返回房间，房间不存在时创建。新建房间计入请求者的创建频率限制和房间总数上限
+func (h *ClipboardHandler) openRoom(c *gin.Context, id string) (*RealRoom, error) {
+	if room := h.Manager.LookupRoom(id); room != nil {
+		return room, nil
+	}
+	if ok, wait := h.createLimit.allow(clientKey(c)); !ok {
+		retryAfter(c, wait)
+		return nil, errRateLimited
+	}
+	return h.Manager.GetRoom(id)
+}
+
+// findRoom 返回已存在的房间（包括其他实例上的），不存在时返回 404，只读取的接口不创建房间
+func (h *ClipboardHandler) findRoom(c *gin.Context, id string) *RealRoom {
+	room := h.Manager.LookupRoom(id)
+	if room == nil {
+		abortWithError(c, errRoomNotFound)
+	}
+	return room
+}
+
+// validateContent 检查要保存的内容：不超过大小上限，加密房间只接受合法的密文信封
+func (h *ClipboardHandler) validateContent(room *RealRoom, content string) error {
+	if len(content) > h.Config.MaxContentSize {
+		return errContentTooLarge
+	}
+	if room.Encrypted {
+		return validateEnvelope(content)
+	}
+	return nil
+}
+
+// maxRequestSize 是保存请求（HTTP 请求体或 WebSocket 消息）的最大字节数
+func (h *ClipboardHandler) maxRequestSize() int {
+	return 2*h.Config.MaxContentSize + requestBodyOverhead
+}
+
+// reserveLocked 为新房间腾出位置：房间数达到上限时淘汰最久未活动且没有客户端连接的房间，
+// 一次淘汰到上限的 95%，避免之后每次创建都遍历所有房间。调用方需持有 m.Mu
+func (m *RealRoomManager) reserveLocked() error {
+	if m.MaxRooms <= 0 || len(m.Rooms) < m.MaxRooms {
+		return nil
+	}
+	m.evictLocked(m.MaxRooms - m.MaxRooms/20 - 1)
+	if len(m.Rooms) >= m.MaxRooms {
+		return errTooManyRooms
+	}
+	return nil
+}
+
+// evictLocked 按最后活动时间从旧到新把没有客户端连接的房间移出内存，直到房间数不超过 target。
+// 房间仍保留在 store 中，下次访问时重新加载（只使用内存存储时无法恢复）。调用方需持有 m.Mu
+func (m *RealRoomManager) evictLocked(target int) {
+	type candidate struct {
+		room       *RealRoom
+		lastActive time.Time
+	}
+	idle := make([]candidate, 0, len(m.Rooms))
+	for _, room := range m.Rooms {
+		room.Mu.Lock()
+		if len(room.Clients) == 0 {
+			idle = append(idle, candidate{room, room.LastActive})
+		}
+		room.Mu.Unlock()
+	}
+	sort.Slice(idle, func(i, j int) bool { return idle[i].lastActive.Before(idle[j].lastActive) })
+
+	evicted := 0
+	for _, cand := range idle {
+		if len(m.Rooms) <= target {
+			break
+		}
+		cand.room.Mu.Lock()
+		if len(cand.room.Clients) == 0 {
+			m.unloadLocked(cand.room)
+			evicted++
+		}
+		cand.room.Mu.Unlock()
+	}
+	m.Metrics.Evicted.Add(int64(evicted))
+	if evicted > 0 {
+		log.Printf("剪贴板房间数达到上限 %d，已淘汰 %d 个最久未使用的房间", m.MaxRooms, evicted)
+	}
+}
@@ -0,0 +1,328 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 协同编辑：非加密房间内容的每次变化都记录为一个文本操作（OT），按修订号保存在 Ops 中。
+// WebSocket 客户端提交基于某个修订号的操作，服务器把它与之后的操作逐一变换后再应用，
+// 因此并发编辑最终收敛到相同的内容。客户端发现修订号不连续（广播被丢弃）时发送 sync 请求，
+// 服务器从操作日志重放缺失的操作，日志不足时返回完整快照。
+//
+// 加密房间的内容对服务器不可见，不记录操作；客户端通过带修订号的整体保存，
+// 在冲突时于本地合并后重试。
+//
+// 位置和长度均以 Unicode 码点计，与客户端的 Array.from(text) 一致。
+const (
+	// MaxRoomOps 是每个房间保留的操作日志条数
+	MaxRoomOps = 200
+	// 单个分量的最大长度，防止恶意的超大数值在求和时溢出
+	maxOpComponent = 1 << 30
+)
+
+var (
+	errInvalidOp     = errors.New("invalid operation")
+	errStaleRevision = errors.New("revision too old")
+)
+
+// OpComponent 是文本操作的一个分量：N > 0 保留 N 个字符，N < 0 删除 -N 个字符，S 非空时插入 S
+// JSON 中分别表示为正数、负数和字符串
+type OpComponent struct {
+	N int
+	S string
+}
+
+func (c OpComponent) MarshalJSON() ([]byte, error) {
+	if c.S != "" {
+		return json.Marshal(c.S)
+	}
+	return json.Marshal(c.N)
+}
+
+func (c *OpComponent) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &c.S)
+	}
+	return json.Unmarshal(b, &c.N)
+}
+
+func (c OpComponent) isInsert() bool { return c.S != "" }
+func (c OpComponent) isRetain() bool { return c.S == "" && c.N > 0 }
+func (c OpComponent) isDelete() bool { return c.S == "" && c.N < 0 }
+
+// TextOp 是作用于整个文档的文本操作
+type TextOp []OpComponent
+
+func (op TextOp) retain(n int) TextOp {
+	if n <= 0 {
+		return op
+	}
+	if last := len(op) - 1; last >= 0 && op[last].isRetain() {
+		op[last].N += n
+		return op
+	}
+	return append(op, OpComponent{N: n})
+}
+
+func (op TextOp) delete(n int) TextOp {
+	if n <= 0 {
+		return op
+	}
+	if last := len(op) - 1; last >= 0 && op[last].isDelete() {
+		op[last].N -= n
+		return op
+	}
+	return append(op, OpComponent{N: -n})
+}
+
+// insert 追加插入分量；插入总是放在相邻的删除之前，使相同效果的操作有唯一表示
+func (op TextOp) insert(s string) TextOp {
+	if s == "" {
+		return op
+	}
+	last := len(op) - 1
+	switch {
+	case last >= 0 && op[last].isInsert():
+		op[last].S += s
+	case last >= 0 && op[last].isDelete():
+		if last >= 1 && op[last-1].isInsert() {
+			op[last-1].S += s
+		} else {
+			op = append(op[:last], OpComponent{S: s}, op[last])
+		}
+	default:
+		op = append(op, OpComponent{S: s})
+	}
+	return op
+}
+
+// lengths 返回操作作用前后的文档长度，分量非法时返回错误
+func (op TextOp) lengths() (base, target int, err error) {
+	for _, c := range op {
+		if c.N > maxOpComponent || c.N < -maxOpComponent {
+			return 0, 0, errInvalidOp
+		}
+		switch {
+		case c.isInsert():
+			if !utf8.ValidString(c.S) {
+				return 0, 0, errInvalidOp
+			}
+			target += utf8.RuneCountInString(c.S)
+		case c.isRetain():
+			base += c.N
+			target += c.N
+		case c.isDelete():
+			base -= c.N
+		default:
+			return 0, 0, errInvalidOp
+		}
+	}
+	return base, target, nil
+}
+
+// apply 将操作应用到文档
+func (op TextOp) apply(doc []rune) ([]rune, error) {
+	base, target, err := op.lengths()
+	if err != nil {
+		return nil, err
+	}
+	if base != len(doc) {
+		return nil, errInvalidOp
+	}
+
+	out := make([]rune, 0, target)
+	i := 0
+	for _, c := range op {
+		switch {
+		case c.isInsert():
+			out = append(out, []rune(c.S)...)
+		case c.isRetain():
+			out = append(out, doc[i:i+c.N]...)
+			i += c.N
+		default:
+			i -= c.N
+		}
+	}
+	return out, nil
+}
+
+// transformOps 变换两个作用于同一文档的并发操作，返回 a' 和 b'，
+// 使 apply(apply(doc, a), b') == apply(apply(doc, b), a')。位置相同的插入 a 在前
+func transformOps(a, b TextOp) (TextOp, TextOp, error) {
+	baseA, _, errA := a.lengths()
+	baseB, _, errB := b.lengths()
+	if errA != nil || errB != nil || baseA != baseB {
+		return nil, nil, errInvalidOp
+	}
+
+	a1, b1 := TextOp{}, TextOp{}
+	i, j := 0, 0
+	next := func(op TextOp, k *int) (OpComponent, bool) {
+		if *k >= len(op) {
+			return OpComponent{}, false
+		}
+		*k++
+		return op[*k-1], true
+	}
+	ca, okA := next(a, &i)
+	cb, okB := next(b, &j)
+
+	for okA || okB {
+		if okA && ca.isInsert() {
+			a1 = a1.insert(ca.S)
+			b1 = b1.retain(utf8.RuneCountInString(ca.S))
+			ca, okA = next(a, &i)
+			continue
+		}
+		if okB && cb.isInsert() {
+			a1 = a1.retain(utf8.RuneCountInString(cb.S))
+			b1 = b1.insert(cb.S)
+			cb, okB = next(b, &j)
+			continue
+		}
+		if !okA || !okB {
+			return nil, nil, errInvalidOp
+		}
+
+		var m int
+		switch {
+		case ca.isRetain() && cb.isRetain():
+			m = min(ca.N, cb.N)
+			a1 = a1.retain(m)
+			b1 = b1.retain(m)
+			ca.N -= m
+			cb.N -= m
+		case ca.isDelete() && cb.isDelete():
+			// 双方删除了相同的字符
+			m = min(-ca.N, -cb.N)
+			ca.N += m
+			cb.N += m
+		case ca.isDelete():
+			m = min(-ca.N, cb.N)
+			a1 = a1.delete(m)
+			ca.N += m
+			cb.N -= m
+		default:
+			m = min(ca.N, -cb.N)
+			b1 = b1.delete(m)
+			ca.N -= m
+			cb.N += m
+		}
+		if ca.N == 0 {
+			ca, okA = next(a, &i)
+		}
+		if cb.N == 0 {
+			cb, okB = next(b, &j)
+		}
+	}
+	return a1, b1, nil
+}
+
+// diffOp 计算把 old 变为 new 的操作（去掉公共前缀和后缀后整体替换中间部分）
+func diffOp(old, new string) TextOp {
+	a, b := []rune(old), []rune(new)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	op := TextOp{}
+	op = op.retain(prefix)
+	op = op.delete(len(a) - prefix - suffix)
+	op = op.insert(string(b[prefix : len(b)-suffix]))
+	op = op.retain(suffix)
+	return op
+}
+
+// RoomOp 是操作日志中的一条记录，Rev 是应用该操作后的修订号
+// 加密房间的记录只有修订号，不含操作
+type RoomOp struct {
+	Rev int64  `json:"rev"`
+	Ops TextOp `json:"ops"`
+	// Format 是该修订改变后的内容类型，类型未变化时为空
+	Format *ContentFormat `json:"format,omitempty"`
+	// Client 是提交操作的 WebSocket 会话，用于在重放时把自己的操作作为确认返回
+	Client string `json:"-"`
+}
+
+// logOp 把操作追加到日志并丢弃超出上限的旧记录，调用方需持有 r.Mu
+func (r *RealRoom) logOp(entry *RoomOp) {
+	r.Ops = append(r.Ops, entry)
+	if len(r.Ops) > MaxRoomOps {
+		r.Ops = append([]*RoomOp(nil), r.Ops[len(r.Ops)-MaxRoomOps:]...)
+	}
+}
+
+// applyOp 把基于修订号 rev 的操作变换到当前版本后应用，调用方需持有 r.Mu
+// seq 是客户端会话内递增的操作序号，已应用过的序号会被忽略（返回 nil），
+// 应用后的内容超过 maxSize 字节时拒绝该操作
+func (r *RealRoom) applyOp(rev int64, op TextOp, author, client string, seq int64, maxSize int) (*RoomOp, error) {
+	if rev < 0 || rev > r.Revision {
+		return nil, errInvalidOp
+	}
+	if seq > 0 && seq <= r.seqs[client] {
+		return nil, nil
+	}
+	first := r.Revision - int64(len(r.Ops))
+	if rev < first {
+		return nil, errStaleRevision
+	}
+
+	for _, logged := range r.Ops[rev-first:] {
+		var err error
+		if op, _, err = transformOps(op, logged.Ops); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := op.apply([]rune(r.Content))
+	if err != nil {
+		return nil, err
+	}
+	content := string(doc)
+	if len(content) > maxSize {
+		return nil, errContentTooLarge
+	}
+
+	entry := r.edit(content, r.Format, op, author, client)
+	entry.Client = client
+	if seq > 0 {
+		if r.seqs == nil {
+			r.seqs = make(map[string]int64)
+		}
+		r.seqs[client] = seq
+	}
+	return entry, nil
+}
+
+// replay 返回修订号 since 之后的操作帧，日志已不完整时返回完整快照，调用方需持有 r.Mu
+func (r *RealRoom) replay(since int64, client string) []wsFrame {
+	first := r.Revision - int64(len(r.Ops))
+	if since < first || since > r.Revision {
+		return []wsFrame{r.snapshotFrame("snapshot", client)}
+	}
+
+	frames := make([]wsFrame, 0, r.Revision-since)
+	for _, logged := range r.Ops[since-first:] {
+		if logged.Client == client {
+			frames = append(frames, wsFrame{Event: "ack", Data: gin.H{"rev": logged.Rev}})
+		} else {
+			frames = append(frames, wsFrame{Event: "op", Data: logged})
+		}
+	}
+	return frames
+}
+
+// snapshotFrame 返回房间完整内容，seq 告诉客户端它提交的操作中最后被应用的序号，调用方需持有 r.Mu
+func (r *RealRoom) snapshotFrame(event, client string) wsFrame {
+	data := r.contentData()
+	data["seq"] = r.seqs[client]
+	return wsFrame{Event: event, Data: data}
+}
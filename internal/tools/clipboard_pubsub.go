@@ -0,0 +1,340 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// 多实例部署：每个实例在内存中保存房间，并通过 RoomBroadcaster 把本地的变化发布给其他实例。
+// 发布的内容有三类：房间状态快照（内容变化、附件、创建）、推送给客户端的事件（操作、输入提示、在线设备）
+// 以及房间删除。收到快照的实例按修订号合并状态，修订号相同但内容不同时（两个实例同时保存）
+// 按实例 ID 决定保留哪一方，保证所有实例最终一致。
+//
+// 本实例没有的房间先向其他实例查询（Want），等持有者回复快照后再处理请求，查询超时才视为不存在。
+// 密码、令牌和加密等设置只在房间创建时确定，之后收到的快照不能修改。
+//
+// 跨实例的并发编辑以后保存者为准，需要无损合并时应按房间 ID 把连接路由到同一实例。
+// 附件文件仍保存在接收上传的实例上，多实例时 CLIPBOARD_DATA_DIR 的附件目录应位于共享存储。
+
+// busQueueSize 是等待发布的消息数上限，发布在后台进行，不阻塞持有房间锁的调用方
+const busQueueSize = 1024
+
+var errBusFull = errors.New("broadcast queue full")
+
+// RoomMessage 是实例之间传递的消息
+type RoomMessage struct {
+	// Origin 是发布消息的实例 ID，实例忽略自己发布的消息
+	Origin string `json:"origin"`
+	Room   string `json:"room"`
+	// Snapshot 是变化后的房间状态，Op 是产生该修订号的操作（如果有）
+	Snapshot *RoomSnapshot `json:"snapshot,omitempty"`
+	Op       *RoomOp       `json:"op,omitempty"`
+	// Deleted 表示房间已到期或被阅后即焚删除
+	Deleted bool `json:"deleted,omitempty"`
+	// Want 表示发送方本地没有该房间，请持有该房间的实例发布快照
+	Want bool `json:"want,omitempty"`
+	// Event 是推送给其他实例上客户端的事件
+	Event  string `json:"event,omitempty"`
+	Data   any    `json:"data,omitempty"`
+	Sender string `json:"sender,omitempty"`
+}
+
+// RoomBroadcaster 定义实例之间的消息总线
+// Publish 不应阻塞，Subscribe 注册的回调按发布顺序在后台 goroutine 中调用（包括本实例发布的消息）
+type RoomBroadcaster interface {
+	Publish(msg RoomMessage) error
+	Subscribe(handler func(RoomMessage)) error
+	Close() error
+}
+
+// NewRoomBroadcaster 根据地址创建消息总线：地址为空时只在进程内转发，
+// redis://[:密码@]主机[:端口][?channel=频道] 使用 Redis 的 PUBLISH/SUBSCRIBE
+func NewRoomBroadcaster(rawURL string) (RoomBroadcaster, error) {
+	if rawURL == "" {
+		return NewMemoryBroadcaster(), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析消息总线地址失败: %w", err)
+	}
+	switch u.Scheme {
+	case "redis":
+		return NewRedisBroadcaster(u)
+	default:
+		return nil, fmt.Errorf("不支持的消息总线: %s", u.Scheme)
+	}
+}
+
+// MemoryBroadcaster 在同一进程内的订阅者之间转发消息
+// 单实例部署时只有一个订阅者，消息不会离开发布者，因此不做任何处理
+type MemoryBroadcaster struct {
+	handlers []func(RoomMessage)
+	queue    chan RoomMessage
+	done     chan struct{}
+	once     sync.Once
+	mu       sync.RWMutex
+}
+
+func NewMemoryBroadcaster() *MemoryBroadcaster {
+	b := &MemoryBroadcaster{
+		queue: make(chan RoomMessage, busQueueSize),
+		done:  make(chan struct{}),
+	}
+	go b.dispatch()
+	return b
+}
+
+func (b *MemoryBroadcaster) Publish(msg RoomMessage) error {
+	if b.subscribers() < 2 {
+		return nil
+	}
+	select {
+	case b.queue <- msg:
+		return nil
+	default:
+		return errBusFull
+	}
+}
+
+func (b *MemoryBroadcaster) Subscribe(handler func(RoomMessage)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *MemoryBroadcaster) Close() error {
+	b.once.Do(func() { close(b.done) })
+	return nil
+}
+
+// subscribers 返回订阅者数量
+func (b *MemoryBroadcaster) subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.handlers)
+}
+
+// dispatch 把消息编码后分别解码交给每个订阅者，使订阅者之间不共享任何数据，行为与网络总线一致
+func (b *MemoryBroadcaster) dispatch() {
+	for {
+		select {
+		case msg := <-b.queue:
+			payload, err := json.Marshal(msg)
+			if err != nil {
+				log.Printf("编码剪贴板消息失败: %v", err)
+				continue
+			}
+			b.mu.RLock()
+			handlers := b.handlers
+			b.mu.RUnlock()
+			for _, handler := range handlers {
+				var copied RoomMessage
+				if err := json.Unmarshal(payload, &copied); err == nil {
+					handler(copied)
+				}
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// shared 表示总线上可能有其他实例：进程内总线只有本实例订阅时房间只存在于本实例
+func (m *RealRoomManager) shared() bool {
+	if b, ok := m.Bus.(*MemoryBroadcaster); ok {
+		return b.subscribers() > 1
+	}
+	return true
+}
+
+// relay 发布本实例产生的消息
+func (m *RealRoomManager) relay(msg RoomMessage) {
+	msg.Origin = m.origin
+	if err := m.Bus.Publish(msg); err != nil {
+		log.Printf("发布剪贴板房间 %s 的消息失败: %v", msg.Room, err)
+	}
+}
+
+// add 把房间加入管理器并接入消息总线，调用方需持有 m.Mu
+func (m *RealRoomManager) add(room *RealRoom) {
+	room.relay = func(msg RoomMessage) {
+		msg.Room = room.ID
+		m.relay(msg)
+	}
+	m.Rooms[room.ID] = room
+}
+
+// relayEvent 把事件转发给其他实例上的客户端，调用方需持有 r.Mu
+func (r *RealRoom) relayEvent(ev RoomEvent) {
+	if r.relay != nil {
+		r.relay(RoomMessage{Event: ev.Name, Data: ev.Data, Sender: ev.Sender, Op: ev.Op})
+	}
+}
+
+// relayState 发布房间的当前状态，调用方需持有 r.Mu
+func (r *RealRoom) relayState() {
+	if r.relay == nil {
+		return
+	}
+	snap := r.snapshot()
+	msg := RoomMessage{Snapshot: &snap}
+	// 刚记录的操作随快照一起发布，其他实例据此延续自己的操作日志
+	if n := len(r.Ops); n > 0 && r.Ops[n-1].Rev == r.Revision {
+		msg.Op = r.Ops[n-1]
+	}
+	r.relay(msg)
+}
+
+// receive 处理其他实例发布的消息
+func (m *RealRoomManager) receive(msg RoomMessage) {
+	if msg.Origin == m.origin || msg.Room == "" {
+		return
+	}
+
+	switch {
+	case msg.Want:
+		// 刚创建还没有内容的房间也要回复，查询方需要它的设置
+		if room := m.loadRoom(msg.Room); room != nil {
+			room.Mu.Lock()
+			snap := room.snapshot()
+			room.relay(RoomMessage{Snapshot: &snap})
+			room.Mu.Unlock()
+		}
+	case msg.Deleted:
+		m.Mu.Lock()
+		if room, ok := m.Rooms[msg.Room]; ok {
+			room.Mu.Lock()
+			m.deleteLocked(room)
+			room.Mu.Unlock()
+		} else {
+			// 已移出内存的房间仍在本地存储中
+			m.deleteStored(msg.Room)
+		}
+		m.Mu.Unlock()
+	case msg.Snapshot != nil:
+		m.receiveState(msg)
+	case msg.Event != "":
+		m.Mu.RLock()
+		room, ok := m.Rooms[msg.Room]
+		m.Mu.RUnlock()
+		if ok {
+			room.Mu.Lock()
+			room.receiveEvent(msg)
+			room.Mu.Unlock()
+		}
+	}
+}
+
+// receiveState 合并其他实例发布的房间状态并写入本地存储
+func (m *RealRoomManager) receiveState(msg RoomMessage) {
+	snap := *msg.Snapshot
+	snap.ID = msg.Room
+
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+
+	room := m.loadRoomLocked(snap.ID)
+	if room == nil {
+		// 本实例第一次见到该房间，设置以快照为准
+		room = roomFromSnapshot(snap)
+		if time.Now().After(room.expiresAt(m.IdleTTL)) {
+			return
+		}
+		if err := m.reserveLocked(); err != nil {
+			log.Printf("无法同步剪贴板房间 %s: %v", snap.ID, err)
+			return
+		}
+		m.add(room)
+		room.Mu.Lock()
+		m.save(room)
+		room.Mu.Unlock()
+		if wait, ok := m.wants[snap.ID]; ok {
+			close(wait)
+			delete(m.wants, snap.ID)
+		}
+		return
+	}
+
+	room.Mu.Lock()
+	defer room.Mu.Unlock()
+
+	if !room.sameSettings(snap) {
+		log.Printf("忽略剪贴板房间 %s 设置不一致的快照", snap.ID)
+		return
+	}
+	room.Attachments = snap.Attachments
+	if snap.LastActive.After(room.LastActive) {
+		room.LastActive = snap.LastActive
+	}
+
+	newer := snap.Revision > room.Revision ||
+		snap.Revision == room.Revision && snap.Content != room.Content && msg.Origin > m.origin
+	if newer {
+		continued := msg.Op != nil && msg.Op.Rev == snap.Revision && snap.Revision == room.Revision+1
+		room.loadContent(snap)
+		if continued {
+			// 随后到达的 message 事件会把该操作推送给本地客户端
+			room.logOp(msg.Op)
+		} else {
+			// 操作日志无法延续，本地客户端需要重新获取完整内容
+			room.Ops = nil
+			room.deliver(RoomEvent{Name: "message", Data: room.contentData()})
+		}
+	} else if snap.Revision == room.Revision && snap.Content == room.Content {
+		// 内容已通过操作同步，只补上作者和历史版本
+		room.loadContent(snap)
+	}
+	m.save(room)
+}
+
+// applyRemoteOp 应用其他实例随事件发布的操作。操作产生的快照是延迟发布的，
+// 本实例据此保持内容和操作日志最新；只接受紧接当前修订号的操作，跳号时等待快照补齐。调用方需持有 r.Mu
+func (r *RealRoom) applyRemoteOp(entry *RoomOp) {
+	if r.Encrypted || entry.Ops == nil || entry.Rev != r.Revision+1 {
+		return
+	}
+	doc, err := entry.Ops.apply([]rune(r.Content))
+	if err != nil {
+		return
+	}
+	r.Content = string(doc)
+	if entry.Format != nil {
+		r.Format = *entry.Format
+	}
+	r.Revision = entry.Rev
+	r.UpdatedAt = time.Now()
+	r.session = ""
+	r.logOp(entry)
+}
+
+// receiveEvent 把其他实例的事件推送给本地客户端，调用方需持有 r.Mu
+func (r *RealRoom) receiveEvent(msg RoomMessage) {
+	if msg.Event == "message" && msg.Op != nil {
+		r.applyRemoteOp(msg.Op)
+	}
+	if msg.Event != "presence" {
+		r.deliver(RoomEvent{Name: msg.Event, Data: msg.Data, Sender: msg.Sender, Op: msg.Op})
+		return
+	}
+
+	// 在线设备按实例分别记录，本地客户端看到所有实例的设备
+	var clients []*RoomClient
+	if payload, err := json.Marshal(msg.Data); err == nil {
+		json.Unmarshal(payload, &clients)
+	}
+	if len(clients) == 0 {
+		delete(r.peers, msg.Origin)
+	} else {
+		if r.peers == nil {
+			r.peers = make(map[string][]*RoomClient)
+		}
+		r.peers[msg.Origin] = clients
+	}
+	r.deliver(RoomEvent{Name: "presence", Data: r.presence()})
+}
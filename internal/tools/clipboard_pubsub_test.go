@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestReplica 创建一个接入 bus 的剪贴板实例，房间只保存在内存中
+func newTestReplica(t *testing.T, bus RoomBroadcaster) (*ClipboardHandler, *gin.Engine) {
+	t.Helper()
+	files, err := NewAttachmentStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewClipboardHandler(nil, NewMemoryRoomStore(), files, bus, DefaultClipboardConfig())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/clipboard/save/:id", h.HandleSave)
+	r.GET("/api/clipboard/get/:id", h.HandleGet)
+	return h, r
+}
+
+// waitFor 等待 cond 成立，总线在后台投递消息
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// unloadReplica 让实例丢掉房间的本地副本，之后的请求需要向其他实例查询
+func unloadReplica(m *RealRoomManager, id string) {
+	m.Mu.Lock()
+	defer m.Mu.Unlock()
+	if room, ok := m.Rooms[id]; ok {
+		room.Mu.Lock()
+		m.unloadLocked(room)
+		room.Mu.Unlock()
+	}
+}
+
+func hasRoom(m *RealRoomManager, id string) bool {
+	m.Mu.RLock()
+	defer m.Mu.RUnlock()
+	_, ok := m.Rooms[id]
+	return ok
+}
+
+func postSave(r *gin.Engine, id, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/clipboard/save/"+id, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReplicaKeepsProtectedRoomSettings(t *testing.T) {
+	bus := NewMemoryBroadcaster()
+	defer bus.Close()
+	a, _ := newTestReplica(t, bus)
+	b, routerB := newTestReplica(t, bus)
+
+	hash, err := hashRoomPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	room, err := a.Manager.CreateRoom(func() string { return "protected" }, RoomOptions{PasswordHash: hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "replica to receive the room", func() bool { return hasRoom(b.Manager, "protected") })
+	unloadReplica(b.Manager, "protected")
+
+	w := postSave(routerB, "protected", `{"content":"pwned"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated save on replica: got %d %s, want 401", w.Code, w.Body)
+	}
+
+	// 其他实例上同 ID 的房间发布的快照不能改变设置
+	bus.Publish(RoomMessage{Origin: "other", Room: "protected", Snapshot: &RoomSnapshot{Content: "pwned", Revision: 5}})
+	// 消息按顺序投递，收到后一个房间说明前一条快照已经处理
+	bus.Publish(RoomMessage{Origin: "other", Room: "marker", Snapshot: &RoomSnapshot{LastActive: time.Now()}})
+	waitFor(t, "marker room", func() bool { return hasRoom(a.Manager, "marker") })
+
+	room.Mu.Lock()
+	defer room.Mu.Unlock()
+	if !room.Protected() || room.EditToken == "" || room.Content != "" {
+		t.Fatalf("room changed: protected=%v edit token=%q content=%q", room.Protected(), room.EditToken, room.Content)
+	}
+}
+
+func TestReplicaKeepsEncryptedRoomSettings(t *testing.T) {
+	bus := NewMemoryBroadcaster()
+	defer bus.Close()
+	a, _ := newTestReplica(t, bus)
+	b, routerB := newTestReplica(t, bus)
+
+	room, err := a.Manager.CreateRoom(func() string { return "sealed" }, RoomOptions{Encrypted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "replica to receive the room", func() bool { return hasRoom(b.Manager, "sealed") })
+	unloadReplica(b.Manager, "sealed")
+
+	if w := postSave(routerB, "sealed", `{"content":"plain text"}`); w.Code == http.StatusOK {
+		t.Fatalf("plaintext save on replica accepted: %s", w.Body)
+	}
+	room.Mu.Lock()
+	defer room.Mu.Unlock()
+	if !room.Encrypted || room.Content != "" {
+		t.Fatalf("room changed: encrypted=%v content=%q", room.Encrypted, room.Content)
+	}
+}
+
+func TestReplicaLooksUpRoomBeforeNotFound(t *testing.T) {
+	bus := NewMemoryBroadcaster()
+	defer bus.Close()
+	a, _ := newTestReplica(t, bus)
+	b, routerB := newTestReplica(t, bus)
+
+	if _, err := a.Manager.CreateRoom(func() string { return "shared" }, RoomOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "replica to receive the room", func() bool { return hasRoom(b.Manager, "shared") })
+	unloadReplica(b.Manager, "shared")
+
+	w := httptest.NewRecorder()
+	routerB.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/clipboard/get/shared", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get on replica without a local copy: got %d %s, want 200", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	routerB.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/clipboard/get/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("get of unknown room: got %d, want 404", w.Code)
+	}
+}
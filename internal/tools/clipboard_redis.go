@@ -0,0 +1,334 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRedisPort    = "6379"
+	defaultRedisChannel = "c2v2:clipboard"
+
+	redisDialTimeout = 5 * time.Second
+	redisIOTimeout   = 10 * time.Second
+	// 断线重连的等待时间从 redisRetryMin 开始翻倍，最多 redisRetryMax
+	redisRetryMin = 500 * time.Millisecond
+	redisRetryMax = 30 * time.Second
+	// 单条消息的大小上限，需能容纳带历史版本的房间快照
+	redisMaxBulk = 64 << 20
+)
+
+var errRedisProtocol = errors.New("invalid redis reply")
+
+// RedisBroadcaster 通过 Redis 的 PUBLISH/SUBSCRIBE 在实例之间转发消息
+// 只用到 RESP 协议中最基本的几条命令，兼容 Redis、Valkey、KeyDB 等实现
+type RedisBroadcaster struct {
+	addr     string
+	username string
+	password string
+	channel  string
+
+	queue chan []byte
+	done  chan struct{}
+	once  sync.Once
+	mu    sync.Mutex
+	// conns 是当前打开的连接，Close 时一并关闭以结束阻塞的读取
+	conns map[net.Conn]struct{}
+}
+
+// NewRedisBroadcaster 根据 redis://[用户名:密码@]主机[:端口][?channel=频道] 创建总线
+// 连接在后台建立，Redis 暂时不可用时会自动重连
+func NewRedisBroadcaster(u *url.URL) (*RedisBroadcaster, error) {
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("消息总线地址缺少主机: %s", u.Redacted())
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultRedisPort
+	}
+	channel := u.Query().Get("channel")
+	if channel == "" {
+		channel = defaultRedisChannel
+	}
+
+	b := &RedisBroadcaster{
+		addr:    net.JoinHostPort(u.Hostname(), port),
+		channel: channel,
+		queue:   make(chan []byte, busQueueSize),
+		done:    make(chan struct{}),
+		conns:   make(map[net.Conn]struct{}),
+	}
+	if u.User != nil {
+		b.username = u.User.Username()
+		b.password, _ = u.User.Password()
+	}
+	go b.publisher()
+	return b, nil
+}
+
+func (b *RedisBroadcaster) Publish(msg RoomMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case b.queue <- payload:
+		return nil
+	default:
+		return errBusFull
+	}
+}
+
+func (b *RedisBroadcaster) Subscribe(handler func(RoomMessage)) error {
+	go b.subscriber(handler)
+	return nil
+}
+
+func (b *RedisBroadcaster) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+		b.mu.Lock()
+		for conn := range b.conns {
+			conn.Close()
+		}
+		b.mu.Unlock()
+	})
+	return nil
+}
+
+func (b *RedisBroadcaster) closed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait 在重连前等待，总线关闭时返回 false
+func (b *RedisBroadcaster) wait(delay *time.Duration) bool {
+	select {
+	case <-b.done:
+		return false
+	case <-time.After(*delay):
+	}
+	if *delay *= 2; *delay > redisRetryMax {
+		*delay = redisRetryMax
+	}
+	return true
+}
+
+// dial 建立连接并完成认证
+func (b *RedisBroadcaster) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", b.addr, redisDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	if b.closed() {
+		b.mu.Unlock()
+		conn.Close()
+		return nil, net.ErrClosed
+	}
+	b.conns[conn] = struct{}{}
+	b.mu.Unlock()
+
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	if b.password != "" {
+		args := []string{"AUTH", b.password}
+		if b.username != "" {
+			args = []string{"AUTH", b.username, b.password}
+		}
+		if _, err := rc.do(args...); err != nil {
+			b.release(rc)
+			return nil, fmt.Errorf("认证失败: %w", err)
+		}
+	}
+	return rc, nil
+}
+
+func (b *RedisBroadcaster) release(rc *redisConn) {
+	b.mu.Lock()
+	delete(b.conns, rc.conn)
+	b.mu.Unlock()
+	rc.conn.Close()
+}
+
+// publisher 按顺序发布队列中的消息，连接出错时重连
+func (b *RedisBroadcaster) publisher() {
+	var rc *redisConn
+	delay := redisRetryMin
+	for {
+		var payload []byte
+		select {
+		case <-b.done:
+			if rc != nil {
+				b.release(rc)
+			}
+			return
+		case payload = <-b.queue:
+		}
+
+		// 空闲连接可能已被服务器断开，失败后用新连接重试一次
+		for attempt := 0; attempt < 2; attempt++ {
+			for rc == nil {
+				var err error
+				if rc, err = b.dial(); err != nil {
+					if b.closed() {
+						return
+					}
+					log.Printf("连接 Redis %s 失败: %v", b.addr, err)
+					if !b.wait(&delay) {
+						return
+					}
+				}
+			}
+			delay = redisRetryMin
+
+			_, err := rc.do("PUBLISH", b.channel, string(payload))
+			if err == nil {
+				break
+			}
+			b.release(rc)
+			rc = nil
+			if b.closed() {
+				return
+			}
+			if attempt == 1 {
+				log.Printf("发布剪贴板消息到 Redis 失败: %v", err)
+			}
+		}
+	}
+}
+
+// subscriber 订阅频道并把收到的消息交给 handler，断线后自动重新订阅
+func (b *RedisBroadcaster) subscriber(handler func(RoomMessage)) {
+	delay := redisRetryMin
+	for !b.closed() {
+		err := b.subscribeOnce(handler, &delay)
+		if b.closed() {
+			return
+		}
+		log.Printf("Redis 订阅中断: %v", err)
+		if !b.wait(&delay) {
+			return
+		}
+	}
+}
+
+func (b *RedisBroadcaster) subscribeOnce(handler func(RoomMessage), delay *time.Duration) error {
+	rc, err := b.dial()
+	if err != nil {
+		return err
+	}
+	defer b.release(rc)
+
+	if _, err := rc.do("SUBSCRIBE", b.channel); err != nil {
+		return err
+	}
+	*delay = redisRetryMin
+
+	for {
+		// 订阅连接上只有推送，不设读超时，依靠 TCP keepalive 发现断线
+		rc.conn.SetDeadline(time.Time{})
+		reply, err := rc.read()
+		if err != nil {
+			return err
+		}
+		// 推送格式: ["message", 频道, 内容]
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 3 || parts[0] != "message" {
+			continue
+		}
+		payload, ok := parts[2].(string)
+		if !ok {
+			continue
+		}
+		var msg RoomMessage
+		if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+			log.Printf("解析剪贴板消息失败: %v", err)
+			continue
+		}
+		handler(msg)
+	}
+}
+
+// redisConn 是一条 RESP 协议连接
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// do 发送命令并读取一条回复，错误回复作为 error 返回
+func (c *redisConn) do(args ...string) (any, error) {
+	c.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read 读取一条回复：简单字符串和批量字符串返回 string，整数返回 int64，数组返回 []any
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errRedisProtocol
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, errors.New(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n > redisMaxBulk {
+			return nil, errRedisProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n > 1024 {
+			return nil, errRedisProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, errRedisProtocol
+	}
+}
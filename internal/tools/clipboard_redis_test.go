@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis 是只实现 AUTH、SUBSCRIBE 和 PUBLISH 的 RESP 服务器
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+	mu       sync.Mutex
+	// subs 是已订阅的连接及其频道，推送和回复都在 mu 内写入
+	subs map[net.Conn]string
+}
+
+func startFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, password: password, subs: make(map[net.Conn]string)}
+	t.Cleanup(func() {
+		ln.Close()
+		f.dropSubscribers()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) url(channel string) *url.URL {
+	u, _ := url.Parse(fmt.Sprintf("redis://:%s@%s?channel=%s", f.password, f.ln.Addr(), channel))
+	return u
+}
+
+func (f *fakeRedis) subscribers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
+
+// dropSubscribers 断开所有订阅连接，模拟 Redis 重启
+func (f *fakeRedis) dropSubscribers() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for conn := range f.subs {
+		conn.Close()
+		delete(f.subs, conn)
+	}
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer func() {
+		f.mu.Lock()
+		delete(f.subs, conn)
+		f.mu.Unlock()
+		conn.Close()
+	}()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			if args[len(args)-1] == f.password {
+				authed = true
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case !authed:
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+		case cmd == "SUBSCRIBE" && len(args) == 2:
+			f.subs[conn] = args[1]
+			io.WriteString(conn, "*3\r\n"+respBulk("subscribe")+respBulk(args[1])+":1\r\n")
+		case cmd == "PUBLISH" && len(args) == 3:
+			n := 0
+			for sub, channel := range f.subs {
+				if channel == args[1] {
+					io.WriteString(sub, "*3\r\n"+respBulk("message")+respBulk(channel)+respBulk(args[2]))
+					n++
+				}
+			}
+			fmt.Fprintf(conn, ":%d\r\n", n)
+		default:
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+		f.mu.Unlock()
+	}
+}
+
+func respBulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// readRESPCommand 读取客户端发送的命令（批量字符串数组）
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, errRedisProtocol
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, errRedisProtocol
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func receiveMessage(t *testing.T, ch <-chan RoomMessage) RoomMessage {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return RoomMessage{}
+	}
+}
+
+func TestRedisBroadcasterPublishSubscribe(t *testing.T) {
+	redis := startFakeRedis(t, "secret")
+	pub, err := NewRedisBroadcaster(redis.url("rooms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	sub, err := NewRedisBroadcaster(redis.url("rooms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	received := make(chan RoomMessage, 4)
+	sub.Subscribe(func(msg RoomMessage) { received <- msg })
+	waitFor(t, "subscription", func() bool { return redis.subscribers() == 1 })
+
+	snap := RoomSnapshot{ID: "abc", Content: "line 1\r\nline 2", Revision: 3}
+	if err := pub.Publish(RoomMessage{Origin: "a", Room: "abc", Snapshot: &snap}); err != nil {
+		t.Fatal(err)
+	}
+	msg := receiveMessage(t, received)
+	if msg.Origin != "a" || msg.Room != "abc" || msg.Snapshot == nil || msg.Snapshot.Content != snap.Content || msg.Snapshot.Revision != 3 {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	// Redis 断开后重新订阅，之后发布的消息照常送达
+	redis.dropSubscribers()
+	waitFor(t, "resubscription", func() bool { return redis.subscribers() == 1 })
+	if err := pub.Publish(RoomMessage{Origin: "a", Room: "abc", Deleted: true}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receiveMessage(t, received); !msg.Deleted {
+		t.Fatalf("unexpected message after reconnect: %+v", msg)
+	}
+}
+
+func TestRedisBroadcasterRequiresAuth(t *testing.T) {
+	redis := startFakeRedis(t, "secret")
+	u := redis.url("rooms")
+	u.User = url.UserPassword("", "wrong")
+	sub, err := NewRedisBroadcaster(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	sub.Subscribe(func(RoomMessage) {})
+	time.Sleep(200 * time.Millisecond)
+	if n := redis.subscribers(); n != 0 {
+		t.Fatalf("subscribed with a wrong password: %d subscribers", n)
+	}
+}
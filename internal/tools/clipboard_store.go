@@ -0,0 +1,290 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RoomSnapshot 是房间的可持久化快照
+type RoomSnapshot struct {
+	ID         string        `json:"id"`
+	Content    string        `json:"content"`
+	Format     ContentFormat `json:"format,omitzero"`
+	LastActive time.Time     `json:"last_active"`
+	Encrypted  bool          `json:"encrypted,omitempty"`
+	// 受保护房间只保存密码的 bcrypt 哈希
+	PasswordHash []byte `json:"password_hash,omitempty"`
+	EditToken    string `json:"edit_token,omitempty"`
+	ViewToken    string `json:"view_token,omitempty"`
+	// 内容版本号及历史版本
+	Revision  int64          `json:"revision,omitempty"`
+	UpdatedAt time.Time      `json:"updated_at,omitzero"`
+	Author    string         `json:"author,omitempty"`
+	History   []RoomRevision `json:"history,omitempty"`
+	// 附件元数据，文件内容由 AttachmentStore 单独保存
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// 有效期与阅后即焚设置
+	ExpiresAt     time.Time `json:"expires_at,omitzero"`
+	BurnAfterRead bool      `json:"burn_after_read,omitempty"`
+	CreatorToken  string    `json:"creator_token,omitempty"`
+}
+
+// RoomStore 定义剪贴板房间的持久化后端
+// RealRoomManager 启动时通过 Load 恢复房间，之后在内容变化、访问和过期时同步写入，
+// 因数量上限被移出内存的房间再次访问时通过 Get 重新加载
+type RoomStore interface {
+	Load() ([]RoomSnapshot, error)
+	Get(id string) (RoomSnapshot, bool, error)
+	Save(snap RoomSnapshot) error
+	Touch(id string, at time.Time) error
+	Delete(id string) error
+	Close() error
+}
+
+// NewRoomStore 根据数据目录创建存储：目录为空时仅保存在内存中
+func NewRoomStore(dataDir string) (RoomStore, error) {
+	if dataDir == "" {
+		return NewMemoryRoomStore(), nil
+	}
+	return NewFileRoomStore(dataDir)
+}
+
+// MemoryRoomStore 不做任何持久化，房间只存在于 RealRoomManager 的内存中
+type MemoryRoomStore struct{}
+
+func NewMemoryRoomStore() *MemoryRoomStore {
+	return &MemoryRoomStore{}
+}
+
+func (s *MemoryRoomStore) Load() ([]RoomSnapshot, error)       { return nil, nil }
+func (s *MemoryRoomStore) Save(snap RoomSnapshot) error        { return nil }
+func (s *MemoryRoomStore) Touch(id string, at time.Time) error { return nil }
+func (s *MemoryRoomStore) Delete(id string) error              { return nil }
+func (s *MemoryRoomStore) Close() error                        { return nil }
+
+func (s *MemoryRoomStore) Get(id string) (RoomSnapshot, bool, error) {
+	return RoomSnapshot{}, false, nil
+}
+
+const (
+	roomLogFile = "clipboard_rooms.log"
+
+	roomOpSave   = "save"
+	roomOpTouch  = "touch"
+	roomOpDelete = "delete"
+
+	// 日志记录数超过存活房间数的倍数时触发压缩
+	roomLogCompactRatio = 4
+	roomLogCompactMin   = 256
+
+	// roomTouchInterval 内的重复访问不写日志，恢复后的活动时间最多早这么久，对空闲过期无影响
+	roomTouchInterval = time.Minute
+)
+
+// roomLogRecord 是追加日志中的一行
+type roomLogRecord struct {
+	Op   string        `json:"op"`
+	ID   string        `json:"id"`
+	At   time.Time     `json:"at,omitzero"`
+	Room *RoomSnapshot `json:"room,omitempty"`
+}
+
+// FileRoomStore 将房间变更以 JSON Lines 追加写入数据目录下的日志文件
+// 日志增长到一定程度后会以当前状态重写（压缩），重写通过临时文件 + rename 保证原子性
+type FileRoomStore struct {
+	path    string
+	file    *os.File
+	rooms   map[string]RoomSnapshot
+	records int
+	mu      sync.Mutex
+}
+
+func NewFileRoomStore(dataDir string) (*FileRoomStore, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	return &FileRoomStore{
+		path:  filepath.Join(dataDir, roomLogFile),
+		rooms: make(map[string]RoomSnapshot),
+	}, nil
+}
+
+// Load 重放日志恢复房间，并在返回前压缩日志
+func (s *FileRoomStore) Load() ([]RoomSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if f != nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			var rec roomLogRecord
+			// 进程崩溃可能留下写了一半的最后一行，直接跳过
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				continue
+			}
+			s.apply(rec)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	snaps := make([]RoomSnapshot, 0, len(s.rooms))
+	for _, snap := range s.rooms {
+		snaps = append(snaps, snap)
+	}
+	return snaps, nil
+}
+
+// Get 返回日志中记录的房间状态
+func (s *FileRoomStore) Get(id string) (RoomSnapshot, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap, ok := s.rooms[id]
+	return snap, ok, nil
+}
+
+func (s *FileRoomStore) Save(snap RoomSnapshot) error {
+	return s.append(roomLogRecord{Op: roomOpSave, ID: snap.ID, Room: &snap})
+}
+
+// Touch 记录房间的访问时间。每次读取和连接都会访问房间，距上次记录不到 roomTouchInterval 时跳过，
+// 避免读流量推动日志增长和压缩
+func (s *FileRoomStore) Touch(id string, at time.Time) error {
+	s.mu.Lock()
+	snap, ok := s.rooms[id]
+	s.mu.Unlock()
+	if !ok || at.Sub(snap.LastActive) < roomTouchInterval {
+		return nil
+	}
+	return s.append(roomLogRecord{Op: roomOpTouch, ID: id, At: at})
+}
+
+func (s *FileRoomStore) Delete(id string) error {
+	return s.append(roomLogRecord{Op: roomOpDelete, ID: id})
+}
+
+func (s *FileRoomStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// apply 将一条日志记录应用到内存索引，返回记录是否改变了状态
+func (s *FileRoomStore) apply(rec roomLogRecord) bool {
+	switch rec.Op {
+	case roomOpSave:
+		if rec.Room == nil {
+			return false
+		}
+		s.rooms[rec.ID] = *rec.Room
+	case roomOpTouch:
+		snap, ok := s.rooms[rec.ID]
+		if !ok {
+			return false
+		}
+		snap.LastActive = rec.At
+		s.rooms[rec.ID] = snap
+	case roomOpDelete:
+		if _, ok := s.rooms[rec.ID]; !ok {
+			return false
+		}
+		delete(s.rooms, rec.ID)
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *FileRoomStore) append(rec roomLogRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 没有内容的房间不落盘，访问和删除它们无需记录
+	if !s.apply(rec) {
+		return nil
+	}
+
+	if s.file == nil {
+		f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		s.file = f
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.records++
+
+	if s.records > roomLogCompactMin && s.records > len(s.rooms)*roomLogCompactRatio {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact 用当前状态重写日志文件，调用方需持有 s.mu
+func (s *FileRoomStore) compact() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for id, snap := range s.rooms {
+		snap := snap
+		if err := enc.Encode(roomLogRecord{Op: roomOpSave, ID: id, Room: &snap}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	s.records = len(s.rooms)
+	return nil
+}
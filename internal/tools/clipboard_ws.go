@@ -0,0 +1,404 @@
+package tools
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	wsPingInterval = 30 * time.Second
+	// WebSocket 客户端的事件缓冲，编辑操作比整体保存频繁得多
+	wsEventBuffer = 64
+	// 同一客户端的输入提示最多每秒转发一次
+	wsTypingInterval = time.Second
+)
+
+// RoomClient 是连接到房间的一个设备
+type RoomClient struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Transport   string    `json:"transport"`
+	ConnectedAt time.Time `json:"connected_at"`
+	// lagged 表示有事件因缓冲区已满被丢弃
+	lagged atomic.Bool
+	// limitKey 是连接者的限流标识，连接期间的保存计入该标识的频率限制
+	limitKey string
+}
+
+// newRoomClient 创建客户端记录，WebSocket 客户端可以通过 ?session= 在重连后沿用同一 ID，
+// 使服务器能够识别它之前提交的操作
+func newRoomClient(c *gin.Context, transport string) *RoomClient {
+	id := c.Query("session")
+	if len(id) < 16 || !safePathComponent(id) {
+		id = randomToken()
+	}
+	return &RoomClient{
+		ID:          id,
+		Name:        authorHint(c, c.Query("name")),
+		Transport:   transport,
+		ConnectedAt: time.Now(),
+		limitKey:    clientKey(c),
+	}
+}
+
+// join 把客户端加入房间并广播在线设备变化，调用方需持有 r.Mu
+func (r *RealRoom) join(ch chan RoomEvent, client *RoomClient) {
+	r.Clients[ch] = client
+	r.broadcastPresence()
+}
+
+// leave 移除客户端、关闭其事件通道并广播在线设备变化，调用方需持有 r.Mu
+func (r *RealRoom) leave(ch chan RoomEvent) {
+	delete(r.Clients, ch)
+	close(ch)
+	r.broadcastPresence()
+}
+
+// localClients 返回连接到本实例的设备，调用方需持有 r.Mu
+func (r *RealRoom) localClients() []*RoomClient {
+	clients := make([]*RoomClient, 0, len(r.Clients))
+	for _, client := range r.Clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// presence 返回所有实例上的在线设备数量及列表，调用方需持有 r.Mu
+func (r *RealRoom) presence() gin.H {
+	clients := r.localClients()
+	for _, peer := range r.peers {
+		clients = append(clients, peer...)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].ConnectedAt.Before(clients[j].ConnectedAt) })
+	return gin.H{"count": len(clients), "clients": clients}
+}
+
+// broadcastPresence 推送在线设备变化，其他实例只接收本实例的设备列表
+func (r *RealRoom) broadcastPresence() {
+	r.deliver(RoomEvent{Name: "presence", Data: r.presence()})
+	r.relayEvent(RoomEvent{Name: "presence", Data: r.localClients()})
+}
+
+// wsFrame 是服务器发送的 WebSocket 消息
+type wsFrame struct {
+	Event string `json:"event"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// wsRequest 是客户端发送的 WebSocket 消息
+type wsRequest struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	// ContentType 和 Language 在整体保存时同时修改内容类型
+	ContentType string `json:"content_type"`
+	Language    string `json:"language"`
+	Author      string `json:"author"`
+	Rev         *int64 `json:"rev"`
+	Seq         int64  `json:"seq"`
+	Ops         TextOp `json:"ops"`
+}
+
+// sameOrigin 拒绝来自其他站点的 WebSocket 连接，避免跨站劫持使用浏览器中的房间 Cookie
+func sameOrigin(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		// 非浏览器客户端不发送 Origin
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != req.Host {
+		return websocket.ErrBadWebSocketOrigin
+	}
+	return nil
+}
+
+// HandleWebSocket 提供双向的房间连接：推送与 SSE 相同的事件，并接收编辑和输入提示
+//
+// 客户端消息：
+//
+//	{"type":"op","rev":3,"seq":1,"ops":[5,"abc",-2]}  基于修订号 rev 的文本操作（非加密房间）
+//	{"type":"edit","content":"...","rev":3}            整体保存，带 rev 时冲突会返回 conflict，可带 content_type 和 language
+//	{"type":"sync","rev":3}                            请求重放修订号 3 之后的操作
+//	{"type":"typing"}                                  通知其他设备正在输入
+//
+// 服务器事件：连接后先发送 hello 和 message（完整内容及修订号），之后是 op（他人的操作）、
+// ack（自己的操作已应用）、snapshot、conflict、files、presence、typing、expired、ping 和 error。
+// op 和 ack 的修订号连续递增，客户端发现跳号时应发送 sync
+func (h *ClipboardHandler) HandleWebSocket(c *gin.Context) {
+	id := c.Param("id")
+	room, err := h.openRoom(c, id)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	if !h.requireAccess(c, room, accessView) {
+		return
+	}
+	// 权限和阅后即焚状态在握手时确定，连接期间不再变化
+	access := h.resolveAccess(c, room)
+	hidden := h.hidesContent(c, room)
+	client := newRoomClient(c, "websocket")
+
+	server := websocket.Server{
+		Handshake: sameOrigin,
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = h.maxRequestSize()
+			h.serveWebSocket(ws, room, client, access, hidden)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// eventFrame 把房间事件转换为发给某个客户端的消息，返回 false 表示不发送
+func eventFrame(ev RoomEvent, clientID string, hidden bool) (wsFrame, bool) {
+	if ev.Name != "message" {
+		return wsFrame{Event: ev.Name, Data: ev.Data}, ev.Sender != clientID
+	}
+	switch {
+	case hidden:
+		// 阅后即焚房间不向非创建者推送内容
+		return wsFrame{}, false
+	case ev.Op == nil:
+		return wsFrame{Event: "message", Data: ev.Data}, true
+	case ev.Sender == clientID:
+		return wsFrame{Event: "ack", Data: gin.H{"rev": ev.Op.Rev}}, true
+	case ev.Op.Ops != nil:
+		return wsFrame{Event: "op", Data: ev.Op}, true
+	default:
+		// 加密房间没有操作，发送完整内容
+		return wsFrame{Event: "message", Data: ev.Data}, true
+	}
+}
+
+func (h *ClipboardHandler) serveWebSocket(ws *websocket.Conn, room *RealRoom, client *RoomClient, access roomAccess, hidden bool) {
+	defer ws.Close()
+
+	clientChan := make(chan RoomEvent, wsEventBuffer)
+
+	room.Mu.Lock()
+	room.join(clientChan, client)
+	initial := room.snapshotFrame("message", client.ID)
+	room.Mu.Unlock()
+	if hidden {
+		initial = wsFrame{Event: "message", Data: gin.H{"content": "", "rev": 0}}
+	}
+
+	defer func() {
+		room.Mu.Lock()
+		room.leave(clientChan)
+		room.Mu.Unlock()
+	}()
+
+	send := func(frame wsFrame) error {
+		return websocket.JSON.Send(ws, frame)
+	}
+	if send(wsFrame{Event: "hello", Data: gin.H{"id": client.ID, "access": access.String()}}) != nil || send(initial) != nil {
+		return
+	}
+
+	// 重放请求交给写循环处理，保证与广播事件的先后顺序一致
+	resync := make(chan int64, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.readWebSocket(ws, room, client, access, send, resync)
+	}()
+
+	// forward 把房间事件发给客户端，返回 false 表示连接应结束
+	forward := func(ev RoomEvent) bool {
+		if ev.Name == "message" && ev.Op == nil && !hidden && !room.Encrypted {
+			// 内容被整体替换（其他实例的状态或丢失事件后的补发），操作无法延续，改为发送快照
+			room.Mu.Lock()
+			snapshot := room.snapshotFrame("snapshot", client.ID)
+			room.Mu.Unlock()
+			return send(snapshot) == nil
+		}
+		frame, ok := eventFrame(ev, client.ID, hidden)
+		if !ok {
+			return true
+		}
+		return send(frame) == nil && ev.Name != "expired"
+	}
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev := <-clientChan:
+			if !forward(ev) {
+				return
+			}
+			// 缓冲区曾经溢出，补发当前状态
+			if client.lagged.Swap(false) {
+				room.Mu.Lock()
+				events := room.resyncEvents()
+				room.Mu.Unlock()
+				for _, ev := range events {
+					if !forward(ev) {
+						return
+					}
+				}
+			}
+		case since := <-resync:
+			if hidden {
+				continue
+			}
+			room.Mu.Lock()
+			frames := room.replay(since, client.ID)
+			room.Mu.Unlock()
+			for _, frame := range frames {
+				if send(frame) != nil {
+					return
+				}
+			}
+		case <-done:
+			return
+		case <-ticker.C:
+			// 心跳携带当前修订号，客户端据此发现丢失的最后几个操作
+			room.Mu.Lock()
+			rev := room.Revision
+			room.Mu.Unlock()
+			if send(wsFrame{Event: "ping", Data: gin.H{"rev": rev}}) != nil {
+				return
+			}
+		}
+	}
+}
+
+// readWebSocket 处理客户端发来的消息，连接关闭或出错时返回
+func (h *ClipboardHandler) readWebSocket(ws *websocket.Conn, room *RealRoom, client *RoomClient, access roomAccess, send func(wsFrame) error, resync chan<- int64) {
+	sendError := func(err string) {
+		send(wsFrame{Event: "error", Data: gin.H{"error": err}})
+	}
+	// rejectSave 拒绝超过大小或频率限制的保存，code 与 HTTP 接口的状态码一致
+	rejectSave := func(err error, wait time.Duration) {
+		data := gin.H{"error": err.Error(), "code": errorStatus(err)}
+		if wait > 0 {
+			data["retry_after"] = int(math.Ceil(wait.Seconds()))
+		}
+		send(wsFrame{Event: "error", Data: data})
+	}
+	requestReplay := func(since int64) {
+		select {
+		case resync <- since:
+		default:
+		}
+	}
+
+	var lastTyping time.Time
+	for {
+		var req wsRequest
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			return
+		}
+
+		author := cleanAuthor(req.Author)
+		if author == "" {
+			author = client.Name
+		}
+
+		switch req.Type {
+		case "op":
+			if access < accessEdit {
+				sendError("read-only access")
+				continue
+			}
+			if room.Encrypted {
+				sendError("operations are not available in encrypted rooms")
+				continue
+			}
+			if req.Rev == nil {
+				sendError(errInvalidOp.Error())
+				continue
+			}
+			// 操作与整体保存共用保存频率限制，客户端等待后取回快照再重新提交
+			if ok, wait := h.saveLimit.allow(client.limitKey); !ok {
+				rejectSave(errRateLimited, wait)
+				continue
+			}
+
+			room.Mu.Lock()
+			var entry *RoomOp
+			var err error
+			if room.Format.Type == ContentImage {
+				// 图片只能整体替换
+				err = errImageOps
+			} else {
+				entry, err = room.applyOp(*req.Rev, req.Ops, author, client.ID, req.Seq, h.Config.MaxContentSize)
+			}
+			if err == nil && entry != nil {
+				h.Manager.commitOp(room, entry, client.ID)
+			}
+			room.Mu.Unlock()
+
+			switch {
+			case errors.Is(err, errStaleRevision):
+				// 客户端落后太多，操作无法变换，发送快照让客户端在本地合并后重新提交
+				requestReplay(-1)
+			case errors.Is(err, errContentTooLarge):
+				rejectSave(err, 0)
+			case err != nil:
+				sendError(err.Error())
+			}
+		case "edit":
+			if access < accessEdit {
+				sendError("read-only access")
+				continue
+			}
+			if err := h.validateContent(room, req.Content); err != nil {
+				if errorStatus(err) == http.StatusRequestEntityTooLarge {
+					rejectSave(err, 0)
+				} else {
+					sendError(err.Error())
+				}
+				continue
+			}
+			if ok, wait := h.saveLimit.allow(client.limitKey); !ok {
+				rejectSave(errRateLimited, wait)
+				continue
+			}
+
+			room.Mu.Lock()
+			if req.Rev != nil && *req.Rev != room.Revision {
+				conflict := room.snapshotFrame("conflict", client.ID)
+				room.Mu.Unlock()
+				send(conflict)
+				continue
+			}
+			format, err := room.resolveFormat(req.ContentType, req.Language, req.Content)
+			if err != nil {
+				room.Mu.Unlock()
+				sendError(err.Error())
+				continue
+			}
+			// 内容未变化时也产生新修订号，保证提交者总能收到确认
+			entry := room.edit(req.Content, format, nil, author, client.ID)
+			entry.Client = client.ID
+			h.Manager.commit(room, entry, client.ID)
+			room.Mu.Unlock()
+		case "sync":
+			if req.Rev != nil {
+				requestReplay(*req.Rev)
+			}
+		case "typing":
+			if time.Since(lastTyping) < wsTypingInterval {
+				continue
+			}
+			lastTyping = time.Now()
+			room.Mu.Lock()
+			room.broadcastEvent(RoomEvent{Name: "typing", Data: gin.H{"id": client.ID, "name": client.Name}, Sender: client.ID})
+			room.Mu.Unlock()
+		default:
+			sendError("unknown message type")
+		}
+	}
+}
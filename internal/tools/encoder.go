@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"c2v2/internal/pkg/render"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxEncoderRequest 是编码器请求的大小上限
+const maxEncoderRequest = 2 << 20
+
+var errUnknownCodec = errors.New("unknown codec")
+
+// codecOption 是页面中编码下拉框的一项
+type codecOption struct {
+	Value    string
+	LabelKey string
+}
+
+type EncoderTool struct {
+	Render *render.Helper
+}
+
+func NewEncoderTool(r *render.Helper) *EncoderTool {
+	return &EncoderTool{Render: r}
+}
+
+func (t *EncoderTool) Handler(c *gin.Context) {
+	lang := c.GetString("lang")
+	if lang == "" {
+		lang = "en"
+	}
+
+	// HTMX 请求处理
+	if c.GetHeader("HX-Request") == "true" {
+		t.handleConvert(c, lang)
+		return
+	}
+
+	options := make([]codecOption, len(codecNames))
+	for i, name := range codecNames {
+		options[i] = codecOption{Value: name, LabelKey: "enc_codec_" + strings.ReplaceAll(name, "-", "_")}
+	}
+
+	appSchema := map[string]any{
+		"@type":               "SoftwareApplication",
+		"name":                t.Render.Translate(lang, "tool_encoder_title"),
+		"applicationCategory": "DeveloperApplication",
+		"operatingSystem":     "Web",
+		"offers": map[string]string{
+			"@type": "Offer",
+			"price": "0",
+		},
+		"description": t.Render.Translate(lang, "tool_encoder_desc"),
+	}
+
+	faqSchema := map[string]any{
+		"@type": "FAQPage",
+		"mainEntity": []map[string]any{
+			{
+				"@type": "Question",
+				"name":  t.Render.Translate(lang, "enc_seo_faq_1_q"),
+				"acceptedAnswer": map[string]any{
+					"@type": "Answer",
+					"text":  t.Render.Translate(lang, "enc_seo_faq_1_a"),
+				},
+			},
+			{
+				"@type": "Question",
+				"name":  t.Render.Translate(lang, "enc_seo_faq_2_q"),
+				"acceptedAnswer": map[string]any{
+					"@type": "Answer",
+					"text":  t.Render.Translate(lang, "enc_seo_faq_2_a"),
+				},
+			},
+		},
+	}
+
+	graphSchema := map[string]any{
+		"@context": "https://schema.org",
+		"@graph":   []any{appSchema, faqSchema},
+	}
+
+	t.Render.HTML(c, http.StatusOK, "encoder.html", gin.H{
+		"title":       "tool_encoder_page_title",
+		"description": "tool_encoder_page_desc",
+		"keywords":    "tool_encoder_keywords",
+		"SchemaData":  graphSchema,
+		"Codecs":      options,
+	})
+}
+
+// handleConvert 用选定的编码处理输入，返回结果片段。解码失败时指出第一个出错的位置
+func (t *EncoderTool) handleConvert(c *gin.Context, lang string) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxEncoderRequest)
+	input := c.PostForm("input")
+	data := gin.H{}
+
+	var result string
+	var err error
+	cd, ok := codecs[c.PostForm("codec")]
+	switch {
+	case !ok:
+		err = errUnknownCodec
+	case input == "":
+		result = t.Render.Translate(lang, "enc_error_empty")
+		data["isError"] = true
+	case cd.maxInput > 0 && len(input) > cd.maxInput:
+		err = errInputTooLong
+	case c.PostForm("action") == "decode":
+		// 去掉首尾空白后解码，错误位置换算回原始输入
+		text, lead := input, 0
+		if !cd.keepSpace {
+			text = strings.TrimLeftFunc(input, unicode.IsSpace)
+			lead = len(input) - len(text)
+			text = strings.TrimRightFunc(text, unicode.IsSpace)
+		}
+		var decoded []byte
+		decoded, err = cd.decode(text)
+		var codecErr *codecError
+		if errors.As(err, &codecErr) {
+			codecErr.Offset += lead
+		}
+		if err != nil {
+			break
+		}
+		if isBinary(decoded) {
+			// 二进制数据以十六进制显示，过大时只显示开头部分
+			result = hex.Dump(decoded[:min(len(decoded), binaryPreviewSize)])
+			data["binary"] = true
+			data["binarySize"] = len(decoded)
+			data["truncated"] = len(decoded) > binaryPreviewSize
+		} else {
+			result = string(decoded)
+		}
+	default:
+		result = cd.encode([]byte(input))
+	}
+	if err != nil {
+		result = t.errorMessage(lang, input, err)
+		data = gin.H{"isError": true}
+	}
+
+	data["result"] = result
+	data["charCount"] = utf8.RuneCountInString(result)
+	data["byteCount"] = len(result)
+	t.Render.HTML(c, http.StatusOK, "encoder_result.html", data)
+}
+
+// errorMessage 把编码解码错误转换为提示信息
+func (t *EncoderTool) errorMessage(lang, input string, err error) string {
+	var codecErr *codecError
+	switch {
+	case errors.As(err, &codecErr):
+		return t.Render.Translate(lang, "enc_error_offset") + " " + formatOffsets(input, []int{codecErr.Offset})
+	case errors.Is(err, errInputTooLong):
+		return t.Render.Translate(lang, "enc_error_too_long")
+	case errors.Is(err, errUnknownCodec):
+		return t.Render.Translate(lang, "enc_error_codec")
+	}
+	return t.Render.Translate(lang, "enc_error_invalid")
+}
@@ -0,0 +1,350 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/ascii85"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime/quotedprintable"
+	"net/url"
+	"strings"
+)
+
+// maxBase58Input 是 Base58 编码解码的输入上限，进制转换的耗时随长度平方增长
+const maxBase58Input = 16 << 10
+
+// base58Alphabet 是比特币使用的 Base58 字母表，去掉了容易混淆的 0、O、I、l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var errInputTooLong = errors.New("input too long")
+
+// codecError 表示严格解码失败，Offset 是出错位置在输入中的字节偏移，等于输入长度表示输入不完整
+type codecError struct {
+	Offset int
+}
+
+func (e *codecError) Error() string {
+	return fmt.Sprintf("invalid input at offset %d", e.Offset)
+}
+
+// codec 是编码器工具支持的一种编码
+type codec struct {
+	encode func([]byte) string
+	decode func(string) ([]byte, error)
+	// keepSpace 表示解码前不去掉首尾空白，空白对该编码有意义
+	keepSpace bool
+	// maxInput 是输入的字节数上限，0 表示不限制
+	maxInput int
+}
+
+// codecNames 是编码在页面中的显示顺序
+var codecNames = []string{
+	"base32", "base32hex", "base58", "ascii85", "hex", "hexdump", "url-query", "url-path", "quoted-printable",
+}
+
+var codecs = map[string]codec{
+	"base32":           {encode: base32.StdEncoding.EncodeToString, decode: base32Decoder(base32StdAlphabet)},
+	"base32hex":        {encode: base32.HexEncoding.EncodeToString, decode: base32Decoder(base32HexAlphabet)},
+	"base58":           {encode: encodeBase58, decode: decodeBase58, maxInput: maxBase58Input},
+	"ascii85":          {encode: encodeAscii85, decode: decodeAscii85},
+	"hex":              {encode: hex.EncodeToString, decode: decodeHex},
+	"hexdump":          {encode: hex.Dump, decode: decodeHexDump},
+	"url-query":        {encode: func(b []byte) string { return url.QueryEscape(string(b)) }, decode: func(s string) ([]byte, error) { return decodePercent(s, true) }},
+	"url-path":         {encode: func(b []byte) string { return url.PathEscape(string(b)) }, decode: func(s string) ([]byte, error) { return decodePercent(s, false) }},
+	"quoted-printable": {encode: encodeQuotedPrintable, decode: decodeQuotedPrintable, keepSpace: true},
+}
+
+const (
+	base32StdAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	base32HexAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUV"
+)
+
+// base32Decoder 返回严格的 Base32 解码函数，字母必须大写且填充完整
+func base32Decoder(alphabet string) func(string) ([]byte, error) {
+	enc := base32.NewEncoding(alphabet)
+	return func(s string) ([]byte, error) {
+		data, err := enc.DecodeString(s)
+		var corrupt base32.CorruptInputError
+		if !errors.As(err, &corrupt) {
+			return data, err
+		}
+		// 长度不完整时标准库报告的是分组的起始位置，改为指向输入末尾
+		offset := int(corrupt)
+		if offset < len(s) && strings.IndexByte(alphabet, s[offset]) >= 0 &&
+			len(strings.NewReplacer("\r", "", "\n", "").Replace(s))%8 != 0 {
+			offset = len(s)
+		}
+		return nil, &codecError{Offset: offset}
+	}
+}
+
+// encodeBase58 按大端序把数据转换为 58 进制，每个前导零字节编码为一个 '1'
+func encodeBase58(data []byte) string {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+	// digits 是低位在前的 58 进制数字
+	var digits []byte
+	for _, b := range data[zeros:] {
+		carry := int(b)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+
+	out := make([]byte, zeros, zeros+len(digits))
+	for i := range out {
+		out[i] = base58Alphabet[0]
+	}
+	for i := len(digits) - 1; i >= 0; i-- {
+		out = append(out, base58Alphabet[digits[i]])
+	}
+	return string(out)
+}
+
+// decodeBase58 是 encodeBase58 的逆运算，字母表以外的字符（包括空白）都是错误
+func decodeBase58(s string) ([]byte, error) {
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	// digits 是低位在前的 256 进制数字
+	var digits []byte
+	for i := zeros; i < len(s); i++ {
+		carry := strings.IndexByte(base58Alphabet, s[i])
+		if carry < 0 {
+			return nil, &codecError{Offset: i}
+		}
+		for j := range digits {
+			carry += int(digits[j]) * 58
+			digits[j] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry))
+			carry >>= 8
+		}
+	}
+
+	out := make([]byte, zeros, zeros+len(digits))
+	for i := len(digits) - 1; i >= 0; i-- {
+		out = append(out, digits[i])
+	}
+	return out, nil
+}
+
+// encodeAscii85 使用 Adobe 的 <~ ~> 定界符输出 Ascii85
+func encodeAscii85(data []byte) string {
+	out := make([]byte, ascii85.MaxEncodedLen(len(data)))
+	n := ascii85.Encode(out, data)
+	return "<~" + string(out[:n]) + "~>"
+}
+
+// decodeAscii85 解码 Ascii85，定界符可以省略，组内的空白会被忽略
+func decodeAscii85(s string) ([]byte, error) {
+	start, end := 0, len(s)
+	if strings.HasPrefix(s, "<~") {
+		start = 2
+	}
+	if strings.HasSuffix(s[start:], "~>") {
+		end -= 2
+	}
+	body := s[start:end]
+
+	// 只有一个字符的末组无法表示任何字节，标准库会静默丢弃
+	count := 0
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == 'z' && count%5 == 0:
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == '\v':
+		case c < '!' || c > 'u':
+			return nil, &codecError{Offset: start + i}
+		default:
+			count++
+		}
+	}
+	if count%5 == 1 {
+		return nil, &codecError{Offset: end}
+	}
+
+	out := make([]byte, 4*len(body))
+	n, _, err := ascii85.Decode(out, []byte(body), true)
+	var corrupt ascii85.CorruptInputError
+	if errors.As(err, &corrupt) {
+		return nil, &codecError{Offset: start + int(corrupt)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+// decodeHex 解码十六进制文本，字节之间可以有空白，但不能把一个字节拆开
+func decodeHex(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s)/2)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			continue
+		}
+		hi, ok := fromHexChar(c)
+		if !ok {
+			return nil, &codecError{Offset: i}
+		}
+		if i+1 >= len(s) {
+			return nil, &codecError{Offset: len(s)}
+		}
+		lo, ok := fromHexChar(s[i+1])
+		if !ok {
+			return nil, &codecError{Offset: i + 1}
+		}
+		out = append(out, hi<<4|lo)
+		i++
+	}
+	return out, nil
+}
+
+// decodeHexDump 解码 hex.Dump（以及 hexdump -C）格式的文本：
+// 每行是 8 位十六进制偏移、十六进制字节和 |...| 中的字符预览，偏移必须与已解码的字节数一致
+func decodeHexDump(s string) ([]byte, error) {
+	var out []byte
+	for lineStart := 0; lineStart < len(s); {
+		line := s[lineStart:]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		next := lineStart + len(line) + 1
+		body := strings.TrimRight(line, " \t\r")
+		if body == "" {
+			lineStart = next
+			continue
+		}
+		if len(body) < 8 {
+			return nil, &codecError{Offset: lineStart}
+		}
+		offset, err := decodeHex(body[:8])
+		if err != nil || len(offset) != 4 {
+			return nil, &codecError{Offset: lineStart}
+		}
+		if n := int(offset[0])<<24 | int(offset[1])<<16 | int(offset[2])<<8 | int(offset[3]); n != len(out) {
+			return nil, &codecError{Offset: lineStart}
+		}
+		// hexdump -C 的最后一行只有偏移
+		rest := body[8:]
+		if i := strings.IndexByte(rest, '|'); i >= 0 {
+			rest = rest[:i]
+		}
+		data, err := decodeHex(rest)
+		var codecErr *codecError
+		if errors.As(err, &codecErr) {
+			return nil, &codecError{Offset: lineStart + 8 + codecErr.Offset}
+		}
+		out = append(out, data...)
+		lineStart = next
+	}
+	return out, nil
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// decodePercent 严格解码百分号编码：% 后必须是两位十六进制，不允许空白和非 ASCII 字符。
+// 查询参数中的 + 表示空格，路径中的 + 是字面值
+func decodePercent(s string, query bool) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '%':
+			if i+2 >= len(s) {
+				return nil, &codecError{Offset: i}
+			}
+			hi, ok1 := fromHexChar(s[i+1])
+			lo, ok2 := fromHexChar(s[i+2])
+			if !ok1 || !ok2 {
+				return nil, &codecError{Offset: i}
+			}
+			out = append(out, hi<<4|lo)
+			i += 2
+		case c == '+' && query:
+			out = append(out, ' ')
+		case c <= ' ' || c >= 0x7f:
+			return nil, &codecError{Offset: i}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// encodeQuotedPrintable 按 RFC 2045 编码，行长不超过 76 个字符，换行统一为 CRLF
+func encodeQuotedPrintable(data []byte) string {
+	var b bytes.Buffer
+	w := quotedprintable.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	return b.String()
+}
+
+// decodeQuotedPrintable 严格解码 quoted-printable：= 后只能是两位十六进制或软换行，
+// 不允许可打印 ASCII、制表符和换行以外的字符。行尾空白按 RFC 2045 视为传输添加的内容并删除
+func decodeQuotedPrintable(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '=':
+			rest := strings.TrimLeft(s[i+1:], " \t")
+			switch {
+			case strings.HasPrefix(rest, "\r\n"):
+				i = len(s) - len(rest) + 1
+			case strings.HasPrefix(rest, "\n"):
+				i = len(s) - len(rest)
+			case rest == "":
+				// 输入末尾的软换行
+				i = len(s)
+			default:
+				if i+2 >= len(s) {
+					return nil, &codecError{Offset: i}
+				}
+				hi, ok1 := fromHexChar(s[i+1])
+				lo, ok2 := fromHexChar(s[i+2])
+				if !ok1 || !ok2 {
+					return nil, &codecError{Offset: i}
+				}
+				out = append(out, hi<<4|lo)
+				i += 2
+			}
+		case c == ' ' || c == '\t':
+			j := i
+			for j < len(s) && (s[j] == ' ' || s[j] == '\t') {
+				j++
+			}
+			if j < len(s) && s[j] != '\r' && s[j] != '\n' {
+				out = append(out, s[i:j]...)
+			}
+			i = j - 1
+		case c == '\r' || c == '\n' || ('!' <= c && c <= '~'):
+			out = append(out, c)
+		default:
+			return nil, &codecError{Offset: i}
+		}
+	}
+	return out, nil
+}
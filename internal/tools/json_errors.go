@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxJSONDiagnostics 是一次最多显示的问题数
+const maxJSONDiagnostics = 10
+
+// JSON 中常见的非标准写法，都是 JavaScript 允许而 JSON 不允许的
+const (
+	jsonIssueTrailingComma = "trailing_comma"
+	jsonIssueUnquotedKey   = "unquoted_key"
+	jsonIssueSingleQuote   = "single_quote"
+	jsonIssueComment       = "comment"
+)
+
+var jsonIssueMessages = map[string]string{
+	jsonIssueTrailingComma: "trailing comma is not allowed",
+	jsonIssueUnquotedKey:   "object keys must be double-quoted strings",
+	jsonIssueSingleQuote:   "strings must use double quotes",
+	jsonIssueComment:       "comments are not allowed",
+}
+
+// jsonDiagnostic 是 JSON 文本中的一处语法问题，Line 和 Column 从 1 开始，列按字符计数
+type jsonDiagnostic struct {
+	// Kind 是已知问题的类型，为空表示 encoding/json 报告的其他错误
+	Kind    string `json:"kind,omitempty"`
+	Message string `json:"message"`
+	// Offset 和 Length 是出错片段的字节范围
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// cause 是这个问题让 encoding/json 报错的位置，用于去掉重复的报告
+	cause int
+}
+
+// diagnoseJSON 定位解析错误。encoding/json 只报告第一个错误，
+// 再扫描尾随逗号、没有引号的键、单引号和注释，尽量一次列出多处问题。结果按位置排序，至少有一项
+func diagnoseJSON(input string, err error) []jsonDiagnostic {
+	diags := scanJSONIssues(input)
+
+	offset := jsonErrorOffset(err, input)
+	covered := false
+	for _, d := range diags {
+		if d.cause == offset {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		d := jsonDiagnostic{Message: err.Error(), Offset: offset}
+		if offset >= 0 && offset < len(input) {
+			d.Length = 1
+		}
+		diags = append(diags, d)
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].Offset < diags[j].Offset })
+	for i := range diags {
+		d := &diags[i]
+		if d.Kind != "" {
+			d.Message = jsonIssueMessages[d.Kind]
+		}
+		if d.Offset >= 0 {
+			d.Line, d.Column = lineColumn(input, d.Offset)
+		}
+	}
+	return diags
+}
+
+// jsonErrorOffset 返回 *json.SyntaxError 或 *json.UnmarshalTypeError 所指字符的字节偏移，
+// 输入不完整时指向末尾，其他错误返回 -1
+func jsonErrorOffset(err error, input string) int {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var offset int64
+	switch {
+	case errors.As(err, &syntaxErr):
+		if strings.HasPrefix(syntaxErr.Error(), "unexpected end") {
+			return len(input)
+		}
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return -1
+	}
+	// Offset 是出错前已读取的字节数，出错的是最后读取的那个字符
+	return min(max(int(offset)-1, 0), len(input))
+}
+
+// lineColumn 把字节偏移换算为行号和列号
+func lineColumn(input string, offset int) (int, int) {
+	before := input[:offset]
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return strings.Count(before, "\n") + 1, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+// scanJSONIssues 扫描 JSON 文本中常见的非标准写法，遇到错误不会停止。
+// 字符串和注释会被整体跳过，其中的逗号和引号不会误报
+func scanJSONIssues(input string) []jsonDiagnostic {
+	var diags []jsonDiagnostic
+	// objects 记录每层嵌套是否为对象，expectKey 表示当前位置应该是对象的键
+	var objects []bool
+	expectKey := false
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := skipQuoted(input, i)
+			if c == '\'' {
+				diags = append(diags, jsonDiagnostic{Kind: jsonIssueSingleQuote, Offset: i, Length: end - i, cause: i})
+			}
+			expectKey = false
+			i = end
+			continue
+		case isCommentStart(input, i):
+			end := skipComment(input, i)
+			diags = append(diags, jsonDiagnostic{Kind: jsonIssueComment, Offset: i, Length: end - i, cause: i})
+			i = end
+			continue
+		case c == '{':
+			objects = append(objects, true)
+			expectKey = true
+		case c == '[':
+			objects = append(objects, false)
+			expectKey = false
+		case c == '}' || c == ']':
+			if len(objects) > 0 {
+				objects = objects[:len(objects)-1]
+			}
+			expectKey = false
+		case c == ',':
+			if next := skipJSONSpace(input, i+1); next < len(input) && (input[next] == '}' || input[next] == ']') {
+				diags = append(diags, jsonDiagnostic{Kind: jsonIssueTrailingComma, Offset: i, Length: 1, cause: next})
+			}
+			expectKey = len(objects) > 0 && objects[len(objects)-1]
+		case c == ':':
+			expectKey = false
+		case expectKey && isIdentByte(c) && (c < '0' || c > '9'):
+			end := i + 1
+			for end < len(input) && isIdentByte(input[end]) {
+				end++
+			}
+			diags = append(diags, jsonDiagnostic{Kind: jsonIssueUnquotedKey, Offset: i, Length: end - i, cause: i})
+			expectKey = false
+			i = end
+			continue
+		}
+		i++
+	}
+	return diags
+}
+
+// skipQuoted 跳过从 i 开始的字符串，返回结束引号之后的位置。没有结束引号时在行尾停止
+func skipQuoted(input string, i int) int {
+	quote := input[i]
+	for j := i + 1; j < len(input); j++ {
+		switch input[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			return j
+		}
+	}
+	return len(input)
+}
+
+func isCommentStart(input string, i int) bool {
+	return input[i] == '/' && i+1 < len(input) && (input[i+1] == '/' || input[i+1] == '*')
+}
+
+// skipComment 跳过从 i 开始的 // 或 /* */ 注释，行注释不包括结尾的换行
+func skipComment(input string, i int) int {
+	if input[i+1] == '/' {
+		if end := strings.IndexByte(input[i:], '\n'); end >= 0 {
+			return i + end
+		}
+		return len(input)
+	}
+	if end := strings.Index(input[i+2:], "*/"); end >= 0 {
+		return i + 2 + end + 2
+	}
+	return len(input)
+}
+
+// skipJSONSpace 跳过空白和注释，返回下一个有效字符的位置
+func skipJSONSpace(input string, i int) int {
+	for i < len(input) {
+		switch {
+		case input[i] == ' ' || input[i] == '\t' || input[i] == '\r' || input[i] == '\n':
+			i++
+		case isCommentStart(input, i):
+			i = skipComment(input, i)
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// isIdentByte 判断字节能否出现在 JavaScript 标识符中，非 ASCII 字符一律视为可以
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// getErrorContext 获取出错行及前后各一行，并在出错行下方用 ^ 标出出错的片段
+func getErrorContext(input string, d jsonDiagnostic) string {
+	if d.Line <= 0 {
+		return ""
+	}
+	lines := strings.Split(input, "\n")
+	start := max(0, d.Line-2)
+	end := min(len(lines), d.Line+1)
+	width := len(strconv.Itoa(end))
+
+	lineStart := strings.LastIndexByte(input[:d.Offset], '\n') + 1
+	lineEnd := len(input)
+	if i := strings.IndexByte(input[d.Offset:], '\n'); i >= 0 {
+		lineEnd = d.Offset + i
+	}
+
+	var context strings.Builder
+	for i := start; i < end; i++ {
+		lineNum := i + 1
+		marker := "  "
+		if lineNum == d.Line {
+			marker = "> "
+		}
+		fmt.Fprintf(&context, "%s%*d: %s\n", marker, width, lineNum, strings.TrimRight(lines[i], "\r"))
+		if lineNum != d.Line {
+			continue
+		}
+		// 保留出错位置之前的制表符，让 ^ 与字符对齐
+		var caret strings.Builder
+		caret.WriteString(strings.Repeat(" ", len(marker)+width+2))
+		for _, r := range input[lineStart:d.Offset] {
+			if r == '\t' {
+				caret.WriteByte('\t')
+			} else {
+				caret.WriteByte(' ')
+			}
+		}
+		span := utf8.RuneCountInString(strings.TrimRight(input[d.Offset:min(d.Offset+d.Length, lineEnd)], "\r"))
+		caret.WriteString(strings.Repeat("^", max(span, 1)))
+		context.WriteString(caret.String() + "\n")
+	}
+	return context.String()
+}
@@ -4,184 +4,131 @@ import (
 	"bytes"
 	"c2v2/internal/pkg/render"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
-	"strconv"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
-	"gopkg.in/yaml.v2"
 )
 
-// jsonToGoStruct converts JSON data to Go struct definition
-func jsonToGoStruct(data interface{}, structName string) string {
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("type %s struct {\n", structName))
-	generateGoStruct(&sb, data, 1, make(map[string]bool))
-	sb.WriteString("}")
-	return sb.String()
+// formatJSON 以两个空格缩进格式化 JSON 文本，保持键的顺序和数字的写法，解析失败时返回原始错误
+func formatJSON(input string) (string, error) {
+	return formatOrderedJSON(input, "  ", false)
 }
 
-func generateGoStruct(sb *strings.Builder, data interface{}, indent int, visited map[string]bool) {
-	indentStr := strings.Repeat("\t", indent)
-
-	switch v := data.(type) {
-	case map[string]interface{}:
-		// Handle object
-		for key, value := range v {
-			fieldName := toPascalCase(key)
-			fieldType := getGoType(value, visited)
-			sb.WriteString(fmt.Sprintf("%s%s %s `json:\"%s\"`\n", indentStr, fieldName, fieldType, key))
-		}
-	case []interface{}:
-		// Handle array - just process first element for type
-		if len(v) > 0 {
-			elemType := getGoType(v[0], visited)
-			sb.WriteString(fmt.Sprintf("%sItems []%s `json:\"items\"`\n", indentStr, elemType))
-		}
-	default:
-		// Handle primitive types
-		typeStr := getGoType(data, visited)
-		sb.WriteString(fmt.Sprintf("%sValue %s `json:\"value\"`\n", indentStr, typeStr))
+func max(a, b int) int {
+	if a > b {
+		return a
 	}
+	return b
 }
 
-func getGoType(data interface{}, visited map[string]bool) string {
-	switch v := data.(type) {
-	case string:
-		return "string"
-	case float64:
-		if v == float64(int64(v)) {
-			return "int"
-		}
-		return "float64"
-	case bool:
-		return "bool"
-	case map[string]interface{}:
-		// For nested objects, create inline struct
-		var sb strings.Builder
-		sb.WriteString("struct {\n")
-		generateGoStruct(&sb, v, 1, visited)
-		sb.WriteString("\t}")
-		return sb.String()
-	case []interface{}:
-		if len(v) > 0 {
-			elemType := getGoType(v[0], visited)
-			return "[]" + elemType
-		}
-		return "[]interface{}"
-	case nil:
-		return "interface{}"
-	default:
-		return "interface{}"
+func min(a, b int) int {
+	if a < b {
+		return a
 	}
+	return b
 }
 
-// parseJSONError解析JSON解析错误，提取行号和列号信息
-func parseJSONError(err error, input string) (string, int, int) {
-	errorMsg := err.Error()
-
-	// 尝试从错误消息中提取位置信息
-	// 常见的JSON错误格式: "invalid character ',' after array element at line 3, column 15"
-	re := regexp.MustCompile(`at line (\d+), column (\d+)`)
-	matches := re.FindStringSubmatch(errorMsg)
-
-	line := 0
-	column := 0
+type JsonFmtTool struct {
+	Render *render.Helper
+}
 
-	if len(matches) >= 3 {
-		if lineNum, err := strconv.Atoi(matches[1]); err == nil {
-			line = lineNum
-		}
-		if colNum, err := strconv.Atoi(matches[2]); err == nil {
-			column = colNum
-		}
-	}
+func NewJsonFmtTool(r *render.Helper) *JsonFmtTool {
+	return &JsonFmtTool{Render: r}
+}
 
-	// 如果没有找到行号，尝试计算
-	if line == 0 && strings.Contains(errorMsg, "line") {
-		// 简单的行号检测
-		lines := strings.Split(input, "\n")
-		for i, l := range lines {
-			if strings.Contains(l, "{") || strings.Contains(l, "[") {
-				line = i + 1
-				break
-			}
-		}
+// diagnosticMessage 翻译已知类型的问题，encoding/json 的原始错误保持英文
+func (t *JsonFmtTool) diagnosticMessage(lang string, d jsonDiagnostic) string {
+	if d.Kind != "" {
+		return t.Render.Translate(lang, "json_diag_"+d.Kind)
 	}
-
-	return errorMsg, line, column
+	return d.Message
 }
 
-// getErrorContext获取错误行的上下文
-func getErrorContext(input string, errorLine int) string {
-	lines := strings.Split(input, "\n")
-	if errorLine <= 0 || errorLine > len(lines) {
-		return ""
-	}
+// jsonCodeGenerators 是 Go 以外的类型生成操作，键是表单的 action 值，都使用 inferJSONType 推断的类型
+var jsonCodeGenerators = map[string]func(input, rootName string) (string, error){
+	"to_ts":       jsonToTypeScript,
+	"to_rust":     jsonToRust,
+	"to_python":   jsonToPythonDataclasses,
+	"to_pydantic": jsonToPydantic,
+	"to_java":     jsonToJava,
+	"to_kotlin":   jsonToKotlin,
+}
 
-	// 显示错误行和前后几行
-	start := max(0, errorLine-2)
-	end := min(len(lines), errorLine+1)
+// jsonFixView 是修复结果中的一项修改
+type jsonFixView struct {
+	Line    int
+	Column  int
+	Message string
+	Text    string
+}
 
-	var context strings.Builder
-	for i := start; i < end; i++ {
-		lineNum := i + 1
-		marker := "  "
-		if lineNum == errorLine {
-			marker = "> "
+// fixViews 把修改列表转换为带行列和说明的显示项
+func (t *JsonFmtTool) fixViews(lang, input string, fixes []jsonFix) []jsonFixView {
+	views := make([]jsonFixView, len(fixes))
+	for i, f := range fixes {
+		line, column := lineColumn(input, f.Offset)
+		views[i] = jsonFixView{
+			Line:    line,
+			Column:  column,
+			Message: t.Render.Translate(lang, "json_fix_"+f.Kind),
+			Text:    strings.TrimSpace(f.Text),
 		}
-		context.WriteString(fmt.Sprintf("%s%d: %s\n", marker, lineNum, lines[i]))
 	}
-
-	return context.String()
+	return views
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
+// jsonSchemaErrorView 是 Schema 校验结果中的一项错误，Line 为 0 表示无法定位到原始输入
+type jsonSchemaErrorView struct {
+	Pointer string
+	Line    int
+	Column  int
+	Message string
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	offset int
 }
 
-func toPascalCase(s string) string {
-	if s == "" {
-		return ""
+// validateSchema 用 schemaText 校验已经是标准 JSON 的 input。Schema 无效时返回 ok 为 false 和说明，
+// 否则返回按原始输入中位置排列的错误。输入经过 JSON5 转换时原始文本的位置对不上，只报告 JSON Pointer
+func (t *JsonFmtTool) validateSchema(lang, raw, input, schemaText string) (string, []jsonSchemaErrorView, bool) {
+	if strings.TrimSpace(schemaText) == "" {
+		return t.Render.Translate(lang, "json_schema_empty"), nil, false
+	}
+	schema, err := decodeJSONValue(schemaText)
+	if err != nil {
+		d := diagnoseJSON(schemaText, err)[0]
+		return fmt.Sprintf("%s%s %d, %s %d: %s", t.Render.Translate(lang, "json_schema_invalid"),
+			t.Render.Translate(lang, "json_error_line"), d.Line,
+			t.Render.Translate(lang, "json_error_column"), d.Column,
+			t.diagnosticMessage(lang, d)), nil, false
+	}
+	value, err := decodeJSONValue(input)
+	if err != nil {
+		return err.Error(), nil, false
+	}
+	errs, err := validateJSONSchema(schema, value)
+	if err != nil {
+		return t.Render.Translate(lang, "json_schema_invalid") + err.Error(), nil, false
 	}
 
-	// Split by common separators
-	parts := strings.FieldsFunc(s, func(r rune) bool {
-		return r == '_' || r == '-' || r == ' '
-	})
-
-	var result strings.Builder
-	for _, part := range parts {
-		if len(part) > 0 {
-			result.WriteString(strings.ToUpper(part[:1]))
-			if len(part) > 1 {
-				result.WriteString(strings.ToLower(part[1:]))
-			}
+	var offsets map[string]int
+	if json.Valid([]byte(raw)) {
+		offsets = jsonValueOffsets(raw)
+	}
+	views := make([]jsonSchemaErrorView, len(errs))
+	for i, e := range errs {
+		views[i] = jsonSchemaErrorView{Pointer: e.Pointer, Message: e.Message}
+		if offset, ok := offsets[e.Pointer]; ok {
+			views[i].Line, views[i].Column = lineColumn(raw, offset)
+			views[i].offset = offset
 		}
 	}
-
-	return result.String()
-}
-
-type JsonFmtTool struct {
-	Render *render.Helper
-}
-
-func NewJsonFmtTool(r *render.Helper) *JsonFmtTool {
-	return &JsonFmtTool{Render: r}
+	slices.SortStableFunc(views, func(a, b jsonSchemaErrorView) int { return a.offset - b.offset })
+	return "", views, true
 }
 
 func (t *JsonFmtTool) Handler(c *gin.Context) {
@@ -192,37 +139,67 @@ func (t *JsonFmtTool) Handler(c *gin.Context) {
 
 	// HTMX 请求处理
 	if c.GetHeader("HX-Request") == "true" {
-		input := c.PostForm("input")
+		// 错误位置相对于未去掉首尾空白的原始输入，与编辑框中的行号一致
+		raw := c.PostForm("input")
 		action := c.PostForm("action")
+		lenient := c.PostForm("lenient") != ""
 		var result string
 		var isError bool
+		var fixes []jsonFix
+		var schemaErrors []jsonSchemaErrorView
 
-		input = strings.TrimSpace(input)
+		input := strings.TrimSpace(raw)
 
 		if input == "" {
 			result = t.Render.Translate(lang, "json_error_empty")
 			isError = true
 		} else {
-			// 尝试解析 JSON
+			// 修复操作和宽松模式先把 JSON5/JSONC 转换为标准 JSON
+			var err error
+			converted := false
+			if action == "repair" || (lenient && !json.Valid([]byte(raw))) {
+				var repaired string
+				repaired, fixes, err = repairJSON(raw, action == "repair")
+				if err == nil {
+					input, converted = repaired, true
+				}
+			}
+
+			// 尝试解析 JSON。没有转换时解析原始输入，错误偏移才与编辑框中的位置一致
 			var jsonObj interface{}
-			err := json.Unmarshal([]byte(input), &jsonObj)
+			if err == nil {
+				text := raw
+				if converted {
+					text = input
+				}
+				err = json.Unmarshal([]byte(text), &jsonObj)
+			}
 			if err != nil {
-				// 解析错误，获取详细的错误信息
-				errorMsg, errorLine, errorColumn := parseJSONError(err, input)
-				context := getErrorContext(input, errorLine)
+				// 解析错误，列出每处问题的行列和上下文。转换失败时只有一个错误，不再报告 JSON5 的写法
+				var diags []jsonDiagnostic
+				var repairErr *jsonRepairError
+				if errors.As(err, &repairErr) {
+					diags = []jsonDiagnostic{repairErr.diagnostic(raw)}
+				} else {
+					diags = diagnoseJSON(raw, err)
+				}
 
 				var errorDetails strings.Builder
 				errorDetails.WriteString(t.Render.Translate(lang, "json_error_invalid"))
-				errorDetails.WriteString(errorMsg)
-
-				if errorLine > 0 {
-					errorDetails.WriteString(fmt.Sprintf("\n\n%s %d, %s %d",
-						t.Render.Translate(lang, "json_error_line"), errorLine,
-						t.Render.Translate(lang, "json_error_column"), errorColumn))
-					if context != "" {
-						errorDetails.WriteString(fmt.Sprintf("\n\n%s:\n", t.Render.Translate(lang, "json_error_context")))
-						errorDetails.WriteString(context)
+				errorDetails.WriteString(t.diagnosticMessage(lang, diags[0]))
+				errorDetails.WriteString("\n")
+				for _, d := range diags[:min(len(diags), maxJSONDiagnostics)] {
+					if d.Line <= 0 {
+						continue
 					}
+					errorDetails.WriteString(fmt.Sprintf("\n%s %d, %s %d: %s\n",
+						t.Render.Translate(lang, "json_error_line"), d.Line,
+						t.Render.Translate(lang, "json_error_column"), d.Column,
+						t.diagnosticMessage(lang, d)))
+					errorDetails.WriteString(getErrorContext(raw, d))
+				}
+				if len(diags) > maxJSONDiagnostics {
+					errorDetails.WriteString(fmt.Sprintf("\n"+t.Render.Translate(lang, "json_error_more"), len(diags)-maxJSONDiagnostics))
 				}
 
 				result = errorDetails.String()
@@ -244,20 +221,57 @@ func (t *JsonFmtTool) Handler(c *gin.Context) {
 					isError = false
 				case "to_go":
 					// JSON 转 Go Struct
-					result = jsonToGoStruct(jsonObj, "AutoGenerated")
+					result, err = jsonToGoTypes(input, "AutoGenerated", goStructOptions{
+						OmitEmpty: c.PostForm("go_omitempty") != "",
+						Pointers:  c.PostForm("go_pointers") != "",
+					})
+					if err != nil {
+						result = "Error generating Go struct: " + err.Error()
+						isError = true
+					}
+				case "to_ts", "to_rust", "to_python", "to_pydantic", "to_java", "to_kotlin":
+					// JSON 转其他语言的类型定义
+					result, err = jsonCodeGenerators[action](input, "AutoGenerated")
+					if err != nil {
+						result = "Error generating types: " + err.Error()
+						isError = true
+					}
+				case "to_schema":
+					// 从样本推断 JSON Schema
+					result, err = jsonToSchema(input, jsonIndent(c.PostForm("indent")))
+					if err != nil {
+						result = "Error generating JSON Schema: " + err.Error()
+						isError = true
+					}
+				case "validate_schema":
+					// 按粘贴的 Schema 校验
+					var ok bool
+					result, schemaErrors, ok = t.validateSchema(lang, raw, input, c.PostForm("schema"))
+					switch {
+					case !ok:
+						isError = true
+					case len(schemaErrors) == 0:
+						result = t.Render.Translate(lang, "json_schema_valid")
+					default:
+						result = fmt.Sprintf(t.Render.Translate(lang, "json_schema_failed"), len(schemaErrors))
+						if len(schemaErrors) > maxSchemaErrors {
+							schemaErrors = schemaErrors[:maxSchemaErrors]
+						}
+						isError = true
+					}
 				case "to_yaml":
-					// JSON 转 YAML
-					yamlData, err := yaml.Marshal(jsonObj)
+					// JSON 转 YAML，保持键的顺序和数字的精度
+					result, err = jsonToYAML(input)
 					if err != nil {
 						result = "Error converting to YAML: " + err.Error()
 						isError = true
-					} else {
-						result = string(yamlData)
 					}
+				case "sort":
+					// 按键名排序后格式化
+					result, _ = formatOrderedJSON(input, jsonIndent(c.PostForm("indent")), true)
 				default:
-					// 格式化 (默认)
-					formatted, _ := json.MarshalIndent(jsonObj, "", "  ")
-					result = string(formatted)
+					// 格式化 (默认) 和修复，保持键的原始顺序和数字的原始写法
+					result, _ = formatOrderedJSON(input, jsonIndent(c.PostForm("indent")), false)
 				}
 			}
 		}
@@ -266,12 +280,22 @@ func (t *JsonFmtTool) Handler(c *gin.Context) {
 		charCount := utf8.RuneCountInString(result)
 		byteCount := len(result)
 
-		t.Render.HTML(c, http.StatusOK, "json_fmt_result.html", gin.H{
+		data := gin.H{
 			"result":    result,
 			"isError":   isError,
 			"charCount": charCount,
 			"byteCount": byteCount,
-		})
+		}
+		if action == "repair" && !isError {
+			data["repaired"] = true
+			// 模板函数 len 只支持字符串和通用切片，修改数在这里计算
+			data["fixes"] = t.fixViews(lang, raw, fixes)
+			data["fixCount"] = len(fixes)
+		}
+		if len(schemaErrors) > 0 {
+			data["schemaErrors"] = schemaErrors
+		}
+		t.Render.HTML(c, http.StatusOK, "json_fmt_result.html", data)
 		return
 	}
 
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goInitialisms 是 Go 命名中全部大写的缩写，与 golint 的列表一致
+var goInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "GUID": true,
+	"HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true, "QPS": true,
+	"RAM": true, "RHS": true, "RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true, "URL": true,
+	"UTF8": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// goStructOptions 是生成 Go 类型的选项
+type goStructOptions struct {
+	// OmitEmpty 给可选和可空的字段加上 omitempty
+	OmitEmpty bool
+	// Pointers 把可空的字段和可选的结构体字段声明为指针，区分 null 和零值
+	Pointers bool
+}
+
+// goIdentifier 把 JSON 键转换为导出的 Go 标识符
+func goIdentifier(key string) string {
+	return pascalCase(key, goInitialisms)
+}
+
+// jsonToGoTypes 从 JSON 样本生成 Go 类型定义。嵌套对象生成独立的命名类型，数组的全部元素合并后推断类型，
+// 字段按键第一次出现的顺序排列，输出经过 go/format 格式化
+func jsonToGoTypes(input, rootName string, opts goStructOptions) (string, error) {
+	root, err := inferJSONInput(input)
+	if err != nil {
+		return "", err
+	}
+	types := nameJSONTypes(root, rootName, goIdentifier, nil)
+
+	var b strings.Builder
+	if root.Kind != jsonKindObject {
+		b.WriteString("type " + rootName + " " + goTypeName(root) + "\n\n")
+	}
+	for _, t := range types {
+		writeGoStruct(&b, t, opts)
+	}
+
+	formatted, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(formatted), "\n"), nil
+}
+
+// goTypeName 返回类型在 Go 中的写法，null 和混合类型为 any
+func goTypeName(t *jsonType) string {
+	if t == nil {
+		return "any"
+	}
+	switch t.Kind {
+	case jsonKindBool:
+		return "bool"
+	case jsonKindInt:
+		return "int64"
+	case jsonKindFloat:
+		return "float64"
+	case jsonKindString:
+		return "string"
+	case jsonKindObject:
+		return t.Name
+	case jsonKindArray:
+		return "[]" + goTypeName(t.Elem)
+	}
+	return "any"
+}
+
+func writeGoStruct(b *strings.Builder, t *jsonType, opts goStructOptions) {
+	b.WriteString("type " + t.Name + " struct {\n")
+	// 不同的键可能转换为相同的字段名（如 id 和 ID），重复时加上数字后缀
+	used := make(map[string]bool, len(t.Fields))
+	for _, f := range t.Fields {
+		name := goIdentifier(f.Key)
+		for i := 2; used[name]; i++ {
+			name = goIdentifier(f.Key) + strconv.Itoa(i)
+		}
+		used[name] = true
+
+		typ := goTypeName(f.Type)
+		switch f.Type.Kind {
+		case jsonKindBool, jsonKindInt, jsonKindFloat, jsonKindString:
+			if opts.Pointers && f.Type.Nullable {
+				typ = "*" + typ
+			}
+		case jsonKindObject:
+			// omitempty 对结构体不起作用，可选的结构体也用指针
+			if opts.Pointers && (f.Type.Nullable || f.Optional) {
+				typ = "*" + typ
+			}
+		}
+		omitEmpty := opts.OmitEmpty && (f.Optional || f.Type.Nullable)
+		tag, note := goStructTag(f.Key, omitEmpty)
+		line := "\t" + name + " " + typ + " " + tag
+		if note != "" {
+			line += " " + note
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("}\n\n")
+}
+
+// goStructTag 生成 json 标签。键为 - 时需要写成 "-,"，否则表示忽略该字段；
+// encoding/json 无法用标签表示的键（空键、含逗号或引号等字符）写成 "-"，第二个返回值是说明注释
+func goStructTag(key string, omitEmpty bool) (string, string) {
+	if !goTagNameValid(key) {
+		return "`json:\"-\"`", "// key " + strconv.Quote(key) + " cannot be represented in a json tag"
+	}
+	value := key
+	switch {
+	case omitEmpty:
+		value += ",omitempty"
+	case key == "-":
+		value += ","
+	}
+	return "`json:" + strconv.Quote(value) + "`", ""
+}
+
+// goTagNameValid 与 encoding/json 判断标签名的规则一致，不合法的名称会被忽略而改用字段名
+func goTagNameValid(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c) && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+)
+
+// javaKeywords 是不能用作名称的 Java 保留字，加上后缀 _
+var javaKeywords = map[string]bool{
+	"abstract": true, "assert": true, "boolean": true, "break": true, "byte": true, "case": true, "catch": true,
+	"char": true, "class": true, "const": true, "continue": true, "default": true, "do": true, "double": true,
+	"else": true, "enum": true, "extends": true, "final": true, "finally": true, "float": true, "for": true,
+	"goto": true, "if": true, "implements": true, "import": true, "instanceof": true, "int": true,
+	"interface": true, "long": true, "native": true, "new": true, "package": true, "private": true,
+	"protected": true, "public": true, "return": true, "short": true, "static": true, "strictfp": true,
+	"super": true, "switch": true, "synchronized": true, "this": true, "throw": true, "throws": true,
+	"transient": true, "try": true, "void": true, "volatile": true, "while": true, "true": true,
+	"false": true, "null": true, "_": true,
+}
+
+// kotlinKeywords 是 Kotlin 的硬关键字，用反引号括起来
+var kotlinKeywords = map[string]bool{
+	"as": true, "break": true, "class": true, "continue": true, "do": true, "else": true, "false": true,
+	"for": true, "fun": true, "if": true, "in": true, "interface": true, "is": true, "null": true,
+	"object": true, "package": true, "return": true, "super": true, "this": true, "throw": true, "true": true,
+	"try": true, "typealias": true, "typeof": true, "val": true, "var": true, "when": true, "while": true,
+}
+
+// javaReservedTypes 是生成的 Java 代码导入或使用的类型名，对象类型不能与它们同名
+var javaReservedTypes = map[string]bool{
+	"List": true, "JsonProperty": true, "Object": true, "String": true, "Boolean": true, "Long": true,
+	"Double": true, "Record": true, "Override": true,
+}
+
+// kotlinReservedTypes 是生成的 Kotlin 代码导入或使用的类型名
+var kotlinReservedTypes = map[string]bool{
+	"List": true, "Serializable": true, "SerialName": true, "JsonElement": true, "String": true,
+	"Boolean": true, "Long": true, "Double": true, "Any": true, "Unit": true, "Array": true, "Map": true, "Set": true,
+}
+
+// jvmFieldNames 把对象的键转换为不重复的 camelCase 名称
+func jvmFieldNames(t *jsonType, rename func(string) string) []string {
+	names := make([]string, len(t.Fields))
+	used := make(map[string]bool, len(t.Fields))
+	for i, f := range t.Fields {
+		name := rename(camelCase(f.Key))
+		for n := 2; used[name]; n++ {
+			name = rename(camelCase(f.Key) + strconv.Itoa(n))
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// jsonToJava 从 JSON 样本生成 Jackson 可以直接反序列化的 Java record（Java 16+）。
+// 名称与键不同时加上 @JsonProperty，可空或可选的数字和布尔值使用包装类型
+func jsonToJava(input, rootName string) (string, error) {
+	root, err := inferJSONInput(input)
+	if err != nil {
+		return "", err
+	}
+	types := nameJSONTypes(root, rootName, typeIdentifier, javaReservedTypes)
+
+	var body strings.Builder
+	usesProperty, usesList := false, false
+	for i, t := range types {
+		body.WriteString("\n")
+		// 一个文件只能有一个 public 类型
+		if i == 0 {
+			body.WriteString("public ")
+		}
+		body.WriteString("record " + t.Name + "(")
+		names := jvmFieldNames(t, func(name string) string {
+			if javaKeywords[name] {
+				return name + "_"
+			}
+			return name
+		})
+		for j, f := range t.Fields {
+			if j > 0 {
+				body.WriteString(",")
+			}
+			body.WriteString("\n        ")
+			if names[j] != f.Key {
+				body.WriteString("@JsonProperty(" + strconv.Quote(f.Key) + ") ")
+				usesProperty = true
+			}
+			typ := javaTypeName(f.Type, f.Optional || f.Type.Nullable)
+			usesList = usesList || strings.Contains(typ, "List<")
+			body.WriteString(typ + " " + names[j])
+		}
+		if len(t.Fields) > 0 {
+			body.WriteString("\n")
+		}
+		body.WriteString(") {\n}\n")
+	}
+
+	var b strings.Builder
+	if usesProperty {
+		b.WriteString("import com.fasterxml.jackson.annotation.JsonProperty;\n")
+	}
+	if usesList || root.Kind == jsonKindArray {
+		b.WriteString("import java.util.List;\n")
+	}
+	if root.Kind != jsonKindObject {
+		// Java 没有类型别名，根类型写在注释里
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("// " + rootName + ": " + javaTypeName(root, false) + "\n")
+	}
+	b.WriteString(body.String())
+	return strings.TrimSpace(b.String()), nil
+}
+
+// javaTypeName 返回类型在 Java 中的写法，boxed 为 true 时基本类型使用包装类型
+func javaTypeName(t *jsonType, boxed bool) string {
+	if t == nil {
+		return "Object"
+	}
+	boxed = boxed || t.Nullable
+	switch t.Kind {
+	case jsonKindBool:
+		if boxed {
+			return "Boolean"
+		}
+		return "boolean"
+	case jsonKindInt:
+		if boxed {
+			return "Long"
+		}
+		return "long"
+	case jsonKindFloat:
+		if boxed {
+			return "Double"
+		}
+		return "double"
+	case jsonKindString:
+		return "String"
+	case jsonKindObject:
+		return t.Name
+	case jsonKindArray:
+		return "List<" + javaTypeName(t.Elem, true) + ">"
+	}
+	return "Object"
+}
+
+// jsonToKotlin 从 JSON 样本生成 kotlinx.serialization 的 data class。
+// 名称与键不同时加上 @SerialName，可选字段默认为 null，混合类型使用 JsonElement
+func jsonToKotlin(input, rootName string) (string, error) {
+	root, err := inferJSONInput(input)
+	if err != nil {
+		return "", err
+	}
+	types := nameJSONTypes(root, rootName, typeIdentifier, kotlinReservedTypes)
+
+	var body strings.Builder
+	usesSerialName, usesElement := false, false
+	if root.Kind != jsonKindObject {
+		typ := kotlinTypeName(root)
+		usesElement = strings.Contains(typ, "JsonElement")
+		body.WriteString("\ntypealias " + rootName + " = " + typ + "\n")
+	}
+	for _, t := range types {
+		body.WriteString("\n@Serializable\n")
+		// data class 至少需要一个属性
+		if len(t.Fields) == 0 {
+			body.WriteString("class " + t.Name + "\n")
+			continue
+		}
+		body.WriteString("data class " + t.Name + "(\n")
+		names := jvmFieldNames(t, func(name string) string {
+			if kotlinKeywords[name] {
+				return "`" + name + "`"
+			}
+			return name
+		})
+		for j, f := range t.Fields {
+			if strings.Trim(names[j], "`") != f.Key {
+				body.WriteString("    @SerialName(" + strconv.Quote(f.Key) + ")\n")
+				usesSerialName = true
+			}
+			typ := kotlinTypeName(f.Type)
+			usesElement = usesElement || strings.Contains(typ, "JsonElement")
+			if f.Optional && !strings.HasSuffix(typ, "?") {
+				typ += "?"
+			}
+			line := "    val " + names[j] + ": " + typ
+			if f.Optional {
+				line += " = null"
+			}
+			body.WriteString(line + ",\n")
+		}
+		body.WriteString(")\n")
+	}
+
+	var b strings.Builder
+	if usesSerialName {
+		b.WriteString("import kotlinx.serialization.SerialName\n")
+	}
+	b.WriteString("import kotlinx.serialization.Serializable\n")
+	if usesElement {
+		b.WriteString("import kotlinx.serialization.json.JsonElement\n")
+	}
+	b.WriteString(body.String())
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// kotlinTypeName 返回类型在 Kotlin 中的写法，可空类型加上 ?
+func kotlinTypeName(t *jsonType) string {
+	if t == nil {
+		return "JsonElement?"
+	}
+	var name string
+	switch t.Kind {
+	case jsonKindBool:
+		name = "Boolean"
+	case jsonKindInt:
+		name = "Long"
+	case jsonKindFloat:
+		name = "Double"
+	case jsonKindString:
+		name = "String"
+	case jsonKindObject:
+		name = t.Name
+	case jsonKindArray:
+		name = "List<" + kotlinTypeName(t.Elem) + ">"
+	default:
+		// JsonElement 的 null 由 JsonNull 表示，字段本身仍可能缺失
+		return "JsonElement?"
+	}
+	if t.Nullable {
+		name += "?"
+	}
+	return name
+}
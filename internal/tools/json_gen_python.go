@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pythonKeywords 是不能用作属性名的 Python 关键字，加上后缀 _。self 会与 __init__ 的第一个参数冲突
+var pythonKeywords = map[string]bool{
+	"self": true,
+	"and":  true, "as": true, "assert": true, "async": true, "await": true, "break": true, "class": true,
+	"continue": true, "def": true, "del": true, "elif": true, "else": true, "except": true, "finally": true,
+	"for": true, "from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true, "return": true,
+	"try": true, "while": true, "with": true, "yield": true,
+}
+
+// pydanticReserved 是 BaseModel 已有的属性，字段不能与它们同名
+var pydanticReserved = map[string]bool{
+	"copy": true, "dict": true, "json": true, "schema": true, "construct": true, "validate": true,
+	"parse_obj": true, "parse_raw": true, "schema_json": true, "update_forward_refs": true,
+}
+
+// pythonReservedTypes 是生成的 Python 代码导入的名称，类名与它们相同会遮盖导入
+var pythonReservedTypes = map[string]bool{
+	"Any": true, "Optional": true, "BaseModel": true, "Field": true,
+}
+
+// pythonGenerator 生成 dataclass 或 Pydantic 模型，记录用到的 typing 名称以便只导入需要的部分
+type pythonGenerator struct {
+	pydantic     bool
+	usesAny      bool
+	usesOptional bool
+}
+
+// jsonToPythonDataclasses 从 JSON 样本生成 dataclass。字段名改为 snake_case，
+// 与键不同时在行尾注释原始的键；可选字段默认为 None，排在必需字段之后
+func jsonToPythonDataclasses(input, rootName string) (string, error) {
+	return (&pythonGenerator{}).generate(input, rootName)
+}
+
+// jsonToPydantic 从 JSON 样本生成 Pydantic 模型，字段名与键不同时用 alias 对应
+func jsonToPydantic(input, rootName string) (string, error) {
+	return (&pythonGenerator{pydantic: true}).generate(input, rootName)
+}
+
+func (g *pythonGenerator) generate(input, rootName string) (string, error) {
+	root, err := inferJSONInput(input)
+	if err != nil {
+		return "", err
+	}
+	// Python 的类在定义时就会被 Pydantic 解析，被引用的类需要先定义
+	types := dependencyOrder(nameJSONTypes(root, rootName, typeIdentifier, pythonReservedTypes))
+
+	var body strings.Builder
+	for _, t := range types {
+		body.WriteString("\n\n")
+		g.writeClass(&body, t)
+	}
+	if root.Kind != jsonKindObject {
+		body.WriteString("\n\n" + rootName + " = " + g.typeName(root) + "\n")
+	}
+
+	var b strings.Builder
+	b.WriteString("from __future__ import annotations\n\n")
+	if !g.pydantic {
+		b.WriteString("from dataclasses import dataclass\n")
+	}
+	var typing []string
+	if g.usesAny {
+		typing = append(typing, "Any")
+	}
+	if g.usesOptional {
+		typing = append(typing, "Optional")
+	}
+	if len(typing) > 0 {
+		b.WriteString("from typing import " + strings.Join(typing, ", ") + "\n")
+	}
+	if g.pydantic {
+		b.WriteString("\nfrom pydantic import BaseModel, Field\n")
+	}
+	b.WriteString(body.String())
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func (g *pythonGenerator) writeClass(b *strings.Builder, t *jsonType) {
+	if g.pydantic {
+		b.WriteString("class " + t.Name + "(BaseModel):\n")
+	} else {
+		b.WriteString("@dataclass\nclass " + t.Name + ":\n")
+	}
+	if len(t.Fields) == 0 {
+		b.WriteString("    pass\n")
+		return
+	}
+
+	// dataclass 中有默认值的字段必须排在后面
+	fields := make([]*jsonField, 0, len(t.Fields))
+	for _, f := range t.Fields {
+		if !f.Optional || g.pydantic {
+			fields = append(fields, f)
+		}
+	}
+	if !g.pydantic {
+		for _, f := range t.Fields {
+			if f.Optional {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	used := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		name := snakeCase(f.Key)
+		if pythonKeywords[name] || (g.pydantic && (pydanticReserved[name] || strings.HasPrefix(name, "model_"))) {
+			name += "_"
+		}
+		for i := 2; used[name]; i++ {
+			name = snakeCase(f.Key) + "_" + strconv.Itoa(i)
+		}
+		used[name] = true
+
+		typ := g.typeName(f.Type)
+		if f.Optional && !strings.HasPrefix(typ, "Optional[") && typ != "Any" {
+			typ = "Optional[" + typ + "]"
+			g.usesOptional = true
+		}
+		line := "    " + name + ": " + typ
+		switch {
+		case g.pydantic && name != f.Key && f.Optional:
+			line += " = Field(default=None, alias=" + strconv.Quote(f.Key) + ")"
+		case g.pydantic && name != f.Key:
+			line += " = Field(alias=" + strconv.Quote(f.Key) + ")"
+		case f.Optional:
+			line += " = None"
+		}
+		if !g.pydantic && name != f.Key {
+			line += "  # " + strconv.Quote(f.Key)
+		}
+		b.WriteString(line + "\n")
+	}
+}
+
+// typeName 返回类型的注解写法，混合类型为 Any
+func (g *pythonGenerator) typeName(t *jsonType) string {
+	if t == nil {
+		g.usesAny = true
+		return "Any"
+	}
+	var name string
+	switch t.Kind {
+	case jsonKindBool:
+		name = "bool"
+	case jsonKindInt:
+		name = "int"
+	case jsonKindFloat:
+		name = "float"
+	case jsonKindString:
+		name = "str"
+	case jsonKindObject:
+		name = t.Name
+	case jsonKindArray:
+		name = "list[" + g.typeName(t.Elem) + "]"
+	default:
+		// Any 已经包括 None
+		g.usesAny = true
+		return "Any"
+	}
+	if t.Nullable {
+		g.usesOptional = true
+		name = "Optional[" + name + "]"
+	}
+	return name
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+)
+
+// rustKeywords 是不能直接用作字段名的 Rust 关键字，使用原始标识符 r#name
+var rustKeywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true, "continue": true, "dyn": true,
+	"else": true, "enum": true, "extern": true, "false": true, "fn": true, "for": true, "if": true, "impl": true,
+	"in": true, "let": true, "loop": true, "match": true, "mod": true, "move": true, "mut": true, "pub": true,
+	"ref": true, "return": true, "static": true, "struct": true, "trait": true, "true": true, "type": true,
+	"unsafe": true, "use": true, "where": true, "while": true, "abstract": true, "become": true, "box": true,
+	"do": true, "final": true, "macro": true, "override": true, "priv": true, "try": true, "typeof": true,
+	"unsized": true, "virtual": true, "yield": true, "gen": true,
+}
+
+// rustReservedTypes 是 prelude 和 serde 中生成的代码会用到的类型名
+var rustReservedTypes = map[string]bool{
+	"Option": true, "Vec": true, "String": true, "Box": true, "Result": true, "Some": true, "None": true,
+	"Ok": true, "Err": true, "Serialize": true, "Deserialize": true, "Self": true,
+}
+
+// jsonToRust 从 JSON 样本生成 serde 结构体。字段名改为 snake_case，与键不同时加上 rename，
+// 可空或可选的字段使用 Option，混合类型使用 serde_json::Value
+func jsonToRust(input, rootName string) (string, error) {
+	root, err := inferJSONInput(input)
+	if err != nil {
+		return "", err
+	}
+	types := nameJSONTypes(root, rootName, typeIdentifier, rustReservedTypes)
+
+	var b strings.Builder
+	b.WriteString("use serde::{Deserialize, Serialize};\n\n")
+	if root.Kind != jsonKindObject {
+		b.WriteString("pub type " + rootName + " = " + rustTypeName(root) + ";\n\n")
+	}
+	for _, t := range types {
+		b.WriteString("#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]\n")
+		b.WriteString("pub struct " + t.Name + " {\n")
+		used := make(map[string]bool, len(t.Fields))
+		for _, f := range t.Fields {
+			name := snakeCase(f.Key)
+			for i := 2; used[name]; i++ {
+				name = snakeCase(f.Key) + "_" + strconv.Itoa(i)
+			}
+			used[name] = true
+			// self、super 和 crate 不能作为原始标识符
+			if name == "self" || name == "super" || name == "crate" {
+				name += "_"
+			}
+
+			if name != f.Key {
+				b.WriteString("    #[serde(rename = " + strconv.Quote(f.Key) + ")]\n")
+			}
+			if rustKeywords[name] {
+				name = "r#" + name
+			}
+			typ := rustTypeName(f.Type)
+			if f.Optional && !strings.HasPrefix(typ, "Option<") {
+				typ = "Option<" + typ + ">"
+			}
+			if strings.HasPrefix(typ, "Option<") {
+				b.WriteString("    #[serde(skip_serializing_if = \"Option::is_none\")]\n")
+			}
+			b.WriteString("    pub " + name + ": " + typ + ",\n")
+		}
+		b.WriteString("}\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// rustTypeName 返回类型在 Rust 中的写法
+func rustTypeName(t *jsonType) string {
+	if t == nil {
+		return "serde_json::Value"
+	}
+	var name string
+	switch t.Kind {
+	case jsonKindBool:
+		name = "bool"
+	case jsonKindInt:
+		name = "i64"
+	case jsonKindFloat:
+		name = "f64"
+	case jsonKindString:
+		name = "String"
+	case jsonKindObject:
+		name = t.Name
+	case jsonKindArray:
+		name = "Vec<" + rustTypeName(t.Elem) + ">"
+	default:
+		// serde_json::Value 本身可以表示 null
+		return "serde_json::Value"
+	}
+	if t.Nullable {
+		name = "Option<" + name + ">"
+	}
+	return name
+}
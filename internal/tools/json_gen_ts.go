@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+)
+
+// tsIdentifier 匹配不需要加引号的 TypeScript 属性名
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsReservedTypes 是 TypeScript 的内置类型名，同名的接口会遮盖它们
+var tsReservedTypes = map[string]bool{
+	"Array": true, "Object": true, "String": true, "Number": true, "Boolean": true, "Record": true,
+	"Date": true, "Map": true, "Set": true, "Promise": true, "Function": true, "Symbol": true,
+}
+
+// typeIdentifier 把 JSON 键转换为类型名，除 Go 以外的语言不对缩写做特殊处理
+func typeIdentifier(key string) string {
+	return pascalCase(key, nil)
+}
+
+// jsonToTypeScript 从 JSON 样本生成 TypeScript 接口。可选字段加 ?，出现过 null 的类型加上 | null
+func jsonToTypeScript(input, rootName string) (string, error) {
+	root, err := inferJSONInput(input)
+	if err != nil {
+		return "", err
+	}
+	types := nameJSONTypes(root, rootName, typeIdentifier, tsReservedTypes)
+
+	var b strings.Builder
+	if root.Kind != jsonKindObject {
+		b.WriteString("export type " + rootName + " = " + tsTypeName(root) + ";\n\n")
+	}
+	for _, t := range types {
+		b.WriteString("export interface " + t.Name + " {\n")
+		for _, f := range t.Fields {
+			name := f.Key
+			if !tsIdentifier.MatchString(name) {
+				name = quoteJSONString(name)
+			}
+			if f.Optional {
+				name += "?"
+			}
+			b.WriteString("  " + name + ": " + tsTypeName(f.Type) + ";\n")
+		}
+		b.WriteString("}\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// tsTypeName 返回类型在 TypeScript 中的写法，混合类型为 unknown
+func tsTypeName(t *jsonType) string {
+	if t == nil {
+		return "unknown"
+	}
+	var name string
+	switch t.Kind {
+	case jsonKindNull:
+		return "null"
+	case jsonKindBool:
+		name = "boolean"
+	case jsonKindInt, jsonKindFloat:
+		name = "number"
+	case jsonKindString:
+		name = "string"
+	case jsonKindObject:
+		name = t.Name
+	case jsonKindArray:
+		elem := tsTypeName(t.Elem)
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		name = elem + "[]"
+	default:
+		return "unknown"
+	}
+	if t.Nullable {
+		name += " | null"
+	}
+	return name
+}
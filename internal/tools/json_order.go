@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// jsonNode 是保留原始写法的 JSON 值：对象成员保持原有顺序（包括重复的键），数字保留原始字面量
+type jsonNode struct {
+	// kind 是 '{'、'[' 或 0（标量）
+	kind byte
+	// literal 是标量的 JSON 文本
+	literal string
+	// keys 是对象的键，与 values 一一对应
+	keys   []string
+	values []*jsonNode
+}
+
+// parseOrderedJSON 用 token 流解析 JSON。先完整校验一遍，语法错误与 json.Unmarshal 的错误一致
+func parseOrderedJSON(input string) (*jsonNode, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(input), &raw); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return readJSONNode(dec)
+}
+
+func readJSONNode(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		node := &jsonNode{kind: byte(v)}
+		for dec.More() {
+			if v == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			child, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, child)
+		}
+		// 读取结尾的 } 或 ]
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case json.Number:
+		return &jsonNode{literal: v.String()}, nil
+	case string:
+		return &jsonNode{literal: quoteJSONString(v)}, nil
+	case bool:
+		if v {
+			return &jsonNode{literal: "true"}, nil
+		}
+		return &jsonNode{literal: "false"}, nil
+	}
+	return &jsonNode{literal: "null"}, nil
+}
+
+// quoteJSONString 输出 JSON 字符串，不转义 HTML 字符
+func quoteJSONString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// write 以 indent 缩进输出节点，sortKeys 为 true 时对象成员按键名排序（稳定排序，重复的键保持原有先后）
+func (n *jsonNode) write(b *strings.Builder, prefix, indent string, sortKeys bool) {
+	if n.kind == 0 {
+		b.WriteString(n.literal)
+		return
+	}
+	closing := byte(']')
+	if n.kind == '{' {
+		closing = '}'
+	}
+	b.WriteByte(n.kind)
+	if len(n.values) == 0 {
+		b.WriteByte(closing)
+		return
+	}
+
+	order := make([]int, len(n.values))
+	for i := range order {
+		order[i] = i
+	}
+	if sortKeys && n.kind == '{' {
+		slices.SortStableFunc(order, func(a, c int) int { return strings.Compare(n.keys[a], n.keys[c]) })
+	}
+
+	inner := prefix + indent
+	for i, idx := range order {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+		b.WriteString(inner)
+		if n.kind == '{' {
+			b.WriteString(quoteJSONString(n.keys[idx]))
+			b.WriteString(": ")
+		}
+		n.values[idx].write(b, inner, indent, sortKeys)
+	}
+	b.WriteByte('\n')
+	b.WriteString(prefix)
+	b.WriteByte(closing)
+}
+
+// formatOrderedJSON 格式化 JSON 文本，保持键的原始顺序和数字的原始写法，
+// 超出 float64 精度的大整数不会被改写；sortKeys 为 true 时按键名递归排序
+func formatOrderedJSON(input, indent string, sortKeys bool) (string, error) {
+	node, err := parseOrderedJSON(input)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	node.write(&b, "", indent, sortKeys)
+	return b.String(), nil
+}
+
+// jsonToYAML 把 JSON 文本转换为 YAML，对象成员保持原有顺序，数字不经过 float64 转换
+func jsonToYAML(input string) (string, error) {
+	node, err := parseOrderedJSON(input)
+	if err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(node.yamlValue())
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// yamlValue 把节点转换为 yaml.v2 可编码的值，对象转换为保持顺序的 MapSlice
+func (n *jsonNode) yamlValue() interface{} {
+	switch n.kind {
+	case '{':
+		items := make(yaml.MapSlice, len(n.keys))
+		for i, key := range n.keys {
+			items[i] = yaml.MapItem{Key: key, Value: n.values[i].yamlValue()}
+		}
+		return items
+	case '[':
+		items := make([]interface{}, len(n.values))
+		for i, v := range n.values {
+			items[i] = v.yamlValue()
+		}
+		return items
+	}
+	switch n.literal {
+	case "null":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if strings.HasPrefix(n.literal, `"`) {
+		var s string
+		json.Unmarshal([]byte(n.literal), &s)
+		return s
+	}
+	return yamlNumber(n.literal)
+}
+
+// yamlNumber 返回与数字字面量值相同的整数或浮点数。float64 的最短写法与原值不等时
+// （超出 uint64 的整数或有效位数过多的小数）保留原始字面量，输出为字符串而不是丢失精度
+func yamlNumber(literal string) interface{} {
+	if i, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(literal, 10, 64); err == nil {
+		return u
+	}
+	if f, err := strconv.ParseFloat(literal, 64); err == nil {
+		exact, ok1 := new(big.Rat).SetString(literal)
+		shortest, ok2 := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+		if ok1 && ok2 && exact.Cmp(shortest) == 0 {
+			return f
+		}
+	}
+	return literal
+}
+
+// jsonIndent 返回格式化使用的缩进：2 个空格（默认）、4 个空格或制表符
+func jsonIndent(option string) string {
+	switch option {
+	case "4":
+		return "    "
+	case "tab":
+		return "\t"
+	}
+	return "  "
+}
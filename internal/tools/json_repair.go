@@ -0,0 +1,492 @@
+package tools
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 转换为标准 JSON 时所做的修改。前几种是 JSON5/JSONC 的合法写法，宽松模式直接接受；
+// 其余是常见的损坏，只有修复操作才会处理
+const (
+	jsonFixComment       = "comment"
+	jsonFixTrailingComma = "trailing_comma"
+	jsonFixSingleQuote   = "single_quote"
+	jsonFixUnquotedKey   = "unquoted_key"
+	jsonFixNumber        = "number"
+	jsonFixEscape        = "escape"
+	jsonFixLiteral       = "literal"
+	jsonFixNonFinite     = "non_finite"
+	jsonFixMissingComma  = "missing_comma"
+	jsonFixUnclosed      = "unclosed"
+	jsonFixControlChar   = "control_char"
+)
+
+// jsonFix 是一处修改，Offset 是原始输入中的字节偏移，Text 是被修改的原文
+type jsonFix struct {
+	Kind   string
+	Offset int
+	Text   string
+}
+
+// jsonRepairError 表示输入无法转换为标准 JSON，Offset 是出错位置的字节偏移
+type jsonRepairError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *jsonRepairError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Msg, e.Offset)
+}
+
+// diagnostic 把错误转换为带行列的诊断信息
+func (e *jsonRepairError) diagnostic(input string) jsonDiagnostic {
+	d := jsonDiagnostic{Message: e.Msg, Offset: e.Offset}
+	if e.Offset < len(input) {
+		d.Length = 1
+	}
+	d.Line, d.Column = lineColumn(input, e.Offset)
+	return d
+}
+
+// repairJSON 把 JSON5/JSONC 文本转换为标准 JSON：去掉注释和尾随逗号，给键加上引号，
+// 单引号字符串改为双引号，十六进制、带正号或省略整数部分的数字改为十进制。
+// fix 为 true 时还会修复 Python 的 True/False/None、缺少的逗号、未闭合的括号和字符串中的换行，
+// NaN 和 Infinity 替换为 null。返回的 JSON 没有缩进，修改按出现的顺序排列
+func repairJSON(input string, fix bool) (string, []jsonFix, error) {
+	p := &json5Parser{input: input, fix: fix}
+	p.space()
+	if p.pos >= len(input) {
+		return "", nil, p.errorf("empty input")
+	}
+	if err := p.value(); err != nil {
+		return "", p.fixes, err
+	}
+	p.space()
+	if p.pos < len(input) {
+		return "", p.fixes, p.errorf("unexpected content after the top-level value")
+	}
+	// 字符串中的修改在字符串结束后才记录，按位置重新排序
+	sort.SliceStable(p.fixes, func(i, j int) bool { return p.fixes[i].Offset < p.fixes[j].Offset })
+	return p.out.String(), p.fixes, nil
+}
+
+// json5Parser 是递归下降的 JSON5 解析器，边解析边输出标准 JSON
+type json5Parser struct {
+	input string
+	pos   int
+	fix   bool
+	out   strings.Builder
+	fixes []jsonFix
+}
+
+func (p *json5Parser) errorf(format string, args ...any) error {
+	return &jsonRepairError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *json5Parser) record(kind string, offset int, text string) {
+	p.fixes = append(p.fixes, jsonFix{Kind: kind, Offset: offset, Text: text})
+}
+
+func (p *json5Parser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// isLineSeparator 判断是否为 U+2028 或 U+2029，JSON5 把它们当作换行
+func isLineSeparator(r rune) bool {
+	return r == 0x2028 || r == 0x2029
+}
+
+// space 跳过空白和注释。JSON5 的空白还包括不换行空格、BOM 和 Unicode 行分隔符
+func (p *json5Parser) space() {
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\v' || c == '\f':
+			p.pos++
+		case isCommentStart(p.input, p.pos):
+			end := skipComment(p.input, p.pos)
+			p.record(jsonFixComment, p.pos, p.input[p.pos:end])
+			p.pos = end
+		case c == '#' && p.fix:
+			// YAML 和 Python 风格的行注释
+			end := strings.IndexByte(p.input[p.pos:], '\n')
+			if end < 0 {
+				end = len(p.input) - p.pos
+			}
+			p.record(jsonFixComment, p.pos, p.input[p.pos:p.pos+end])
+			p.pos += end
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			if r != 0xA0 && r != 0xFEFF && !isLineSeparator(r) {
+				return
+			}
+			p.pos += size
+		default:
+			return
+		}
+	}
+}
+
+func (p *json5Parser) value() error {
+	p.space()
+	switch c := p.peek(); {
+	case p.pos >= len(p.input):
+		return p.errorf("unexpected end of input")
+	case c == '{':
+		return p.container('{', '}')
+	case c == '[':
+		return p.container('[', ']')
+	case c == '"' || c == '\'':
+		s, err := p.str()
+		if err != nil {
+			return err
+		}
+		p.out.WriteString(quoteJSONString(s))
+		return nil
+	case c == '-' || c == '+' || c == '.' || ('0' <= c && c <= '9'):
+		return p.number()
+	case isIdentByte(c):
+		return p.literalAt(p.pos)
+	default:
+		return p.errorf("unexpected character %q", c)
+	}
+}
+
+// container 解析对象或数组。修复模式下，遇到另一种右括号或输入结束时补上缺少的右括号
+func (p *json5Parser) container(open, close byte) error {
+	start := p.pos
+	p.pos++
+	p.out.WriteByte(open)
+	for i := 0; ; i++ {
+		p.space()
+		c := p.peek()
+		switch {
+		case c == close:
+			p.pos++
+			p.out.WriteByte(close)
+			return nil
+		case p.fix && (p.pos >= len(p.input) || c == '}' || c == ']'):
+			p.record(jsonFixUnclosed, start, string(open))
+			p.out.WriteByte(close)
+			return nil
+		case p.pos >= len(p.input):
+			return p.errorf("unexpected end of input, expected %q", close)
+		}
+
+		if i > 0 {
+			switch {
+			case c == ',':
+				comma := p.pos
+				p.pos++
+				p.space()
+				if next := p.peek(); next == close || p.fix && (p.pos >= len(p.input) || next == '}' || next == ']') {
+					p.record(jsonFixTrailingComma, comma, ",")
+					continue
+				}
+			case p.fix:
+				p.record(jsonFixMissingComma, p.pos, "")
+			default:
+				return p.errorf("expected ',' or %q", close)
+			}
+			p.out.WriteByte(',')
+		}
+
+		if open == '[' {
+			if err := p.value(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.key(); err != nil {
+			return err
+		}
+		p.space()
+		if p.peek() != ':' {
+			return p.errorf("expected ':' after object key")
+		}
+		p.pos++
+		p.out.WriteByte(':')
+		if err := p.value(); err != nil {
+			return err
+		}
+	}
+}
+
+// key 解析对象的键，可以是字符串或标识符；修复模式下也接受以数字开头的键
+func (p *json5Parser) key() error {
+	p.space()
+	c := p.peek()
+	if c == '"' || c == '\'' {
+		s, err := p.str()
+		if err != nil {
+			return err
+		}
+		p.out.WriteString(quoteJSONString(s))
+		return nil
+	}
+	if !isIdentByte(c) || (!p.fix && '0' <= c && c <= '9') {
+		return p.errorf("expected object key")
+	}
+	start := p.pos
+	for p.pos < len(p.input) && isIdentByte(p.input[p.pos]) {
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+	p.record(jsonFixUnquotedKey, start, name)
+	p.out.WriteString(quoteJSONString(name))
+	return nil
+}
+
+// str 解析单引号或双引号字符串，返回解码后的内容。
+// 除 JSON 的转义外还支持 \' \v \0 \xHH 和行尾的 \ 续行，其他字符前的 \ 被忽略
+func (p *json5Parser) str() (string, error) {
+	start := p.pos
+	quote := p.input[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.input) {
+			p.pos = start
+			return "", p.errorf("unterminated string")
+		}
+		c := p.input[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			if quote == '\'' {
+				p.record(jsonFixSingleQuote, start, p.input[start:p.pos])
+			}
+			return b.String(), nil
+		case c == '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+			continue
+		case c == '\n' || c == '\r':
+			if !p.fix {
+				return "", p.errorf("unescaped line break in string")
+			}
+			p.record(jsonFixControlChar, p.pos, string(c))
+		case c < ' ':
+			p.record(jsonFixControlChar, p.pos, string(c))
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+}
+
+// escape 解码从反斜杠开始的转义序列
+func (p *json5Parser) escape(b *strings.Builder) error {
+	start := p.pos
+	p.pos++
+	if p.pos >= len(p.input) {
+		return p.errorf("unterminated string")
+	}
+	c := p.input[p.pos]
+	p.pos++
+	switch c {
+	case '"', '\\', '/':
+		b.WriteByte(c)
+		return nil
+	case 'b':
+		b.WriteByte('\b')
+		return nil
+	case 'f':
+		b.WriteByte('\f')
+		return nil
+	case 'n':
+		b.WriteByte('\n')
+		return nil
+	case 'r':
+		b.WriteByte('\r')
+		return nil
+	case 't':
+		b.WriteByte('\t')
+		return nil
+	case 'u':
+		r, err := p.hexRune(4)
+		if err != nil {
+			return err
+		}
+		// UTF-16 代理对
+		if utf16.IsSurrogate(r) && strings.HasPrefix(p.input[p.pos:], "\\u") {
+			save := p.pos
+			p.pos += 2
+			if low, err := p.hexRune(4); err == nil && utf16.DecodeRune(r, low) != utf8.RuneError {
+				r = utf16.DecodeRune(r, low)
+			} else {
+				p.pos = save
+			}
+		}
+		b.WriteRune(r)
+		return nil
+	}
+
+	// 以下是 JSON5 的转义，标准 JSON 中没有
+	p.record(jsonFixEscape, start, p.input[start:p.pos])
+	switch c {
+	case 'v':
+		b.WriteByte('\v')
+	case '0':
+		b.WriteByte(0)
+	case 'x':
+		r, err := p.hexRune(2)
+		if err != nil {
+			return err
+		}
+		b.WriteRune(r)
+	case '\r':
+		// 续行
+		if p.peek() == '\n' {
+			p.pos++
+		}
+	case '\n':
+	default:
+		// 其他字符前的反斜杠没有作用，反斜杠加 Unicode 行分隔符也是续行
+		r, size := utf8.DecodeRuneInString(p.input[p.pos-1:])
+		p.pos += size - 1
+		if !isLineSeparator(r) {
+			b.WriteRune(r)
+		}
+	}
+	return nil
+}
+
+// hexRune 读取 n 位十六进制数
+func (p *json5Parser) hexRune(n int) (rune, error) {
+	if p.pos+n > len(p.input) {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	v, err := strconv.ParseUint(p.input[p.pos:p.pos+n], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	p.pos += n
+	return rune(v), nil
+}
+
+// number 解析 JSON5 数字并输出标准 JSON 的写法：去掉正号和多余的前导零，
+// 补全省略的整数或小数部分，十六进制转换为十进制
+func (p *json5Parser) number() error {
+	start := p.pos
+	sign := ""
+	if c := p.peek(); c == '+' || c == '-' {
+		if c == '-' {
+			sign = "-"
+		}
+		p.pos++
+	}
+	if c := p.peek(); c == 'I' || c == 'N' {
+		return p.literalAt(start)
+	}
+
+	var literal string
+	if rest := p.input[p.pos:]; len(rest) > 1 && rest[0] == '0' && (rest[1] == 'x' || rest[1] == 'X') {
+		p.pos += 2
+		digits := p.pos
+		for p.pos < len(p.input) && isHexDigit(p.input[p.pos]) {
+			p.pos++
+		}
+		n, ok := new(big.Int).SetString(p.input[digits:p.pos], 16)
+		if !ok {
+			return p.errorf("invalid hexadecimal number")
+		}
+		literal = sign + n.String()
+	} else {
+		intPart := p.digits()
+		frac := ""
+		if p.peek() == '.' {
+			p.pos++
+			frac = p.digits()
+		}
+		if intPart == "" && frac == "" {
+			return p.errorf("invalid number")
+		}
+		exp := ""
+		if c := p.peek(); c == 'e' || c == 'E' {
+			expStart := p.pos
+			p.pos++
+			if c := p.peek(); c == '+' || c == '-' {
+				p.pos++
+			}
+			if p.digits() == "" {
+				return p.errorf("invalid number exponent")
+			}
+			exp = p.input[expStart:p.pos]
+		}
+
+		intPart = strings.TrimLeft(intPart, "0")
+		if intPart == "" {
+			intPart = "0"
+		}
+		literal = sign + intPart
+		if frac != "" {
+			literal += "." + frac
+		}
+		literal += exp
+	}
+	if p.pos < len(p.input) && isIdentByte(p.input[p.pos]) {
+		return p.errorf("invalid number")
+	}
+
+	if original := p.input[start:p.pos]; original != literal {
+		p.record(jsonFixNumber, start, original)
+	}
+	p.out.WriteString(literal)
+	return nil
+}
+
+func (p *json5Parser) digits() string {
+	start := p.pos
+	for p.pos < len(p.input) && '0' <= p.input[p.pos] && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func isHexDigit(c byte) bool {
+	_, ok := fromHexChar(c)
+	return ok
+}
+
+// pythonLiterals 是修复模式下替换的 Python 和 JavaScript 字面量
+var pythonLiterals = map[string]string{"True": "true", "False": "false", "None": "null", "undefined": "null"}
+
+// literalAt 解析从 start 开始（NaN 和 Infinity 可能带正负号）的字面量
+func (p *json5Parser) literalAt(start int) error {
+	for p.pos < len(p.input) && isIdentByte(p.input[p.pos]) {
+		p.pos++
+	}
+	word := p.input[start:p.pos]
+	switch word {
+	case "true", "false", "null":
+		p.out.WriteString(word)
+		return nil
+	case "NaN", "Infinity", "+Infinity", "-Infinity", "+NaN", "-NaN":
+		if !p.fix {
+			p.pos = start
+			return p.errorf("%s cannot be represented in JSON", word)
+		}
+		p.record(jsonFixNonFinite, start, word)
+		p.out.WriteString("null")
+		return nil
+	}
+
+	replacement, ok := pythonLiterals[word]
+	if !ok || !p.fix {
+		p.pos = start
+		return p.errorf("unexpected identifier %q", word)
+	}
+	p.record(jsonFixLiteral, start, word)
+	p.out.WriteString(replacement)
+	return nil
+}
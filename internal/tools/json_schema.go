@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// jsonSchemaDialect 是生成的 Schema 使用的草案版本
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonToSchema 从 JSON 样本推断 draft 2020-12 的 JSON Schema。所有样本中都出现的字段列入 required，
+// 出现过 null 的位置使用 ["类型", "null"]，混合类型不加约束
+func jsonToSchema(input, indent string) (string, error) {
+	root, err := inferJSONInput(input)
+	if err != nil {
+		return "", err
+	}
+	schema := schemaNode(root)
+	// $schema 放在最前面
+	schema.keys = append([]string{"$schema"}, schema.keys...)
+	schema.values = append([]*jsonNode{{literal: quoteJSONString(jsonSchemaDialect)}}, schema.values...)
+
+	var b strings.Builder
+	schema.write(&b, "", indent, false)
+	return b.String(), nil
+}
+
+// schemaNode 把推断出的类型转换为 Schema 对象
+func schemaNode(t *jsonType) *jsonNode {
+	n := &jsonNode{kind: '{'}
+	if t == nil || t.Kind == jsonKindAny {
+		return n
+	}
+	set := func(key string, value *jsonNode) {
+		n.keys = append(n.keys, key)
+		n.values = append(n.values, value)
+	}
+
+	name := map[jsonKind]string{
+		jsonKindNull:   "null",
+		jsonKindBool:   "boolean",
+		jsonKindInt:    "integer",
+		jsonKindFloat:  "number",
+		jsonKindString: "string",
+		jsonKindObject: "object",
+		jsonKindArray:  "array",
+	}[t.Kind]
+	if t.Nullable && t.Kind != jsonKindNull {
+		set("type", &jsonNode{kind: '[', values: []*jsonNode{
+			{literal: quoteJSONString(name)},
+			{literal: `"null"`},
+		}})
+	} else {
+		set("type", &jsonNode{literal: quoteJSONString(name)})
+	}
+
+	switch t.Kind {
+	case jsonKindObject:
+		properties := &jsonNode{kind: '{'}
+		required := &jsonNode{kind: '['}
+		for _, f := range t.Fields {
+			properties.keys = append(properties.keys, f.Key)
+			properties.values = append(properties.values, schemaNode(f.Type))
+			if !f.Optional {
+				required.values = append(required.values, &jsonNode{literal: quoteJSONString(f.Key)})
+			}
+		}
+		set("properties", properties)
+		if len(required.values) > 0 {
+			set("required", required)
+		}
+	case jsonKindArray:
+		// 空数组无法推断元素类型
+		if t.Elem != nil {
+			set("items", schemaNode(t.Elem))
+		}
+	}
+	return n
+}
+
+// jsonPointerEscape 按 RFC 6901 转义 JSON Pointer 中的一段
+func jsonPointerEscape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// jsonPointerUnescape 还原转义过的一段 JSON Pointer
+func jsonPointerUnescape(token string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+}
+
+// jsonValueOffsets 返回每个值的 JSON Pointer 到它在 input 中起始字节偏移的映射。
+// 重复的键记录最后一个，与解码为 map 时保留的值一致
+func jsonValueOffsets(input string) map[string]int {
+	offsets := make(map[string]int)
+	dec := json.NewDecoder(strings.NewReader(input))
+	var walk func(pointer string) bool
+	walk = func(pointer string) bool {
+		// InputOffset 位于上一个 token 之后，跳过空白、逗号和冒号就是值的开头
+		offset := int(dec.InputOffset())
+		for offset < len(input) && strings.IndexByte(" \t\r\n,:", input[offset]) >= 0 {
+			offset++
+		}
+		offsets[pointer] = offset
+
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return true
+		}
+		for i := 0; dec.More(); i++ {
+			child := pointer + "/" + strconv.Itoa(i)
+			if delim == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return false
+				}
+				child = pointer + "/" + jsonPointerEscape(key.(string))
+			}
+			if !walk(child) {
+				return false
+			}
+		}
+		_, err = dec.Token()
+		return err == nil
+	}
+	walk("")
+	return offsets
+}
@@ -0,0 +1,575 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaErrors 是最多报告的校验错误数
+const maxSchemaErrors = 50
+
+// unsupportedSchemaKeywords 依赖其他关键字的注释结果，没有实现。
+// 不能忽略它们而报告校验通过，遇到时作为 Schema 错误报告
+var unsupportedSchemaKeywords = []string{"unevaluatedProperties", "unevaluatedItems", "$dynamicRef", "$recursiveRef"}
+
+// schemaVisit 是正在校验的 Schema 和值的组合，同一组合再次出现说明 $ref 形成了循环
+type schemaVisit struct {
+	schema  uintptr
+	pointer string
+}
+
+// schemaError 是一处不符合 Schema 的值，Pointer 是值在输入中的 JSON Pointer
+type schemaError struct {
+	Pointer string
+	Message string
+}
+
+// schemaValidator 按 draft 2020-12 校验 JSON。支持常用的校验关键字和文档内的 $ref，
+// format 按规范只作注释；unevaluatedProperties 等依赖注释结果的关键字以及外部引用不支持，遇到时报错
+type schemaValidator struct {
+	root     any
+	anchors  map[string]any
+	patterns map[string]*regexp.Regexp
+	// active 是递归中正在校验的组合，用于发现循环的 $ref
+	active map[schemaVisit]bool
+	// err 记录 Schema 本身的问题，如无效的正则或无法解析的 $ref
+	err error
+}
+
+// decodeJSONValue 解析 JSON，数字保留为 json.Number 以便精确比较。语法错误与 json.Unmarshal 的错误一致
+func decodeJSONValue(input string) (any, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(input), &raw); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// validateJSONSchema 用 schema 校验 value，返回按输入中出现顺序排列的错误。Schema 本身有问题时返回 error
+func validateJSONSchema(schema, value any) ([]schemaError, error) {
+	v := &schemaValidator{
+		root:     schema,
+		anchors:  make(map[string]any),
+		patterns: make(map[string]*regexp.Regexp),
+		active:   make(map[schemaVisit]bool),
+	}
+	switch schema.(type) {
+	case bool, map[string]any:
+	default:
+		return nil, errors.New("schema must be an object or a boolean")
+	}
+	v.collectAnchors(schema)
+	errs := v.validate(schema, value, "")
+	if v.err != nil {
+		return nil, v.err
+	}
+	return errs, nil
+}
+
+// collectAnchors 记录 Schema 中所有的 $anchor，用于解析 #name 形式的引用
+func (v *schemaValidator) collectAnchors(schema any) {
+	switch s := schema.(type) {
+	case map[string]any:
+		if anchor, ok := s["$anchor"].(string); ok {
+			v.anchors[anchor] = s
+		}
+		for _, sub := range s {
+			v.collectAnchors(sub)
+		}
+	case []any:
+		for _, sub := range s {
+			v.collectAnchors(sub)
+		}
+	}
+}
+
+// fail 记录 Schema 的问题，只保留第一个
+func (v *schemaValidator) fail(format string, args ...any) {
+	if v.err == nil {
+		v.err = fmt.Errorf(format, args...)
+	}
+}
+
+// resolve 解析文档内的 $ref：#、#/json/pointer 和 #anchor，以及以根 $id 开头的同一文档引用
+func (v *schemaValidator) resolve(ref string) (any, bool) {
+	if root, ok := v.root.(map[string]any); ok {
+		if id, ok := root["$id"].(string); ok && id != "" && strings.HasPrefix(ref, id) {
+			ref = "#" + strings.TrimPrefix(ref[len(id):], "#")
+		}
+	}
+	if !strings.HasPrefix(ref, "#") {
+		v.fail("unsupported $ref %q: only references within the schema are supported", ref)
+		return nil, false
+	}
+	fragment, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		v.fail("invalid $ref %q: %v", ref, err)
+		return nil, false
+	}
+	if fragment == "" {
+		return v.root, true
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		target, ok := v.anchors[fragment]
+		if !ok {
+			v.fail("unresolved $ref %q: no $anchor named %q", ref, fragment)
+		}
+		return target, ok
+	}
+
+	node := v.root
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = jsonPointerUnescape(token)
+		var ok bool
+		switch n := node.(type) {
+		case map[string]any:
+			node, ok = n[token]
+		case []any:
+			var i int
+			i, err = strconv.Atoi(token)
+			if ok = err == nil && i >= 0 && i < len(n); ok {
+				node = n[i]
+			}
+		}
+		if !ok {
+			v.fail("unresolved $ref %q", ref)
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// pattern 编译并缓存正则。Go 的 RE2 不支持前后断言和反向引用，这类模式作为 Schema 错误报告
+func (v *schemaValidator) pattern(expr string) *regexp.Regexp {
+	if re, ok := v.patterns[expr]; ok {
+		return re
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		v.fail("unsupported pattern %q: %v", expr, err)
+	}
+	v.patterns[expr] = re
+	return re
+}
+
+// matches 判断 value 是否符合 schema，用于 anyOf、oneOf、not、if 和 contains
+func (v *schemaValidator) matches(schema, value any, pointer string) bool {
+	return len(v.validate(schema, value, pointer)) == 0
+}
+
+func (v *schemaValidator) validate(schema, value any, pointer string) []schemaError {
+	if v.err != nil {
+		return nil
+	}
+
+	var errs []schemaError
+	add := func(format string, args ...any) {
+		errs = append(errs, schemaError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var s map[string]any
+	switch sc := schema.(type) {
+	case bool:
+		if !sc {
+			add("no value is allowed here")
+		}
+		return errs
+	case map[string]any:
+		s = sc
+	default:
+		v.fail("schema at %q must be an object or a boolean", pointer)
+		return nil
+	}
+	for _, keyword := range unsupportedSchemaKeywords {
+		if _, ok := s[keyword]; ok {
+			v.fail("unsupported keyword %q", keyword)
+			return nil
+		}
+	}
+
+	// 数据每深入一层 pointer 都会变化，只有不消耗数据的 $ref 循环才会回到同一个组合
+	visit := schemaVisit{schema: reflect.ValueOf(s).Pointer(), pointer: pointer}
+	if v.active[visit] {
+		v.fail("circular $ref at %q", pointer)
+		return nil
+	}
+	v.active[visit] = true
+	defer delete(v.active, visit)
+
+	if ref, ok := s["$ref"].(string); ok {
+		if target, ok := v.resolve(ref); ok {
+			errs = append(errs, v.validate(target, value, pointer)...)
+		}
+	}
+
+	// 通用关键字
+	if t, ok := s["type"]; ok && !schemaTypeMatches(t, value) {
+		add("expected %s, got %s", schemaTypeList(t), jsonValueType(value))
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return jsonEqual(e, value) }) {
+		add("value must be one of %s", compactJSONValue(enum))
+	}
+	if c, ok := s["const"]; ok && !jsonEqual(c, value) {
+		add("value must be %s", compactJSONValue(c))
+	}
+
+	// 组合关键字
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			errs = append(errs, v.validate(sub, value, pointer)...)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok && !slices.ContainsFunc(anyOf, func(sub any) bool {
+		return v.matches(sub, value, pointer)
+	}) {
+		add("value does not match any schema in anyOf")
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if v.matches(sub, value, pointer) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			add("value matches %d schemas in oneOf, expected exactly 1", matched)
+		}
+	}
+	if not, ok := s["not"]; ok && v.matches(not, value, pointer) {
+		add("value must not match the schema in not")
+	}
+	if cond, ok := s["if"]; ok {
+		branch := "else"
+		if v.matches(cond, value, pointer) {
+			branch = "then"
+		}
+		if sub, ok := s[branch]; ok {
+			errs = append(errs, v.validate(sub, value, pointer)...)
+		}
+	}
+
+	switch val := value.(type) {
+	case json.Number:
+		errs = append(errs, v.validateNumber(s, val, pointer)...)
+	case string:
+		n := utf8.RuneCountInString(val)
+		if limit, ok := schemaInt(s["minLength"]); ok && n < limit {
+			add("string has %d characters, fewer than minLength %d", n, limit)
+		}
+		if limit, ok := schemaInt(s["maxLength"]); ok && n > limit {
+			add("string has %d characters, more than maxLength %d", n, limit)
+		}
+		if expr, ok := s["pattern"].(string); ok {
+			if re := v.pattern(expr); re != nil && !re.MatchString(val) {
+				add("string does not match pattern %q", expr)
+			}
+		}
+	case []any:
+		errs = append(errs, v.validateArray(s, val, pointer)...)
+	case map[string]any:
+		errs = append(errs, v.validateObject(s, val, pointer)...)
+	}
+	return errs
+}
+
+func (v *schemaValidator) validateNumber(s map[string]any, val json.Number, pointer string) []schemaError {
+	var errs []schemaError
+	x, ok := jsonRat(val)
+	if !ok {
+		return nil
+	}
+	check := func(keyword string, fails func(limit *big.Rat) bool, format string) {
+		raw, ok := s[keyword].(json.Number)
+		if !ok {
+			return
+		}
+		if limit, ok := jsonRat(raw); ok && fails(limit) {
+			errs = append(errs, schemaError{Pointer: pointer, Message: fmt.Sprintf(format, val, raw)})
+		}
+	}
+	check("minimum", func(m *big.Rat) bool { return x.Cmp(m) < 0 }, "%s is less than minimum %s")
+	check("maximum", func(m *big.Rat) bool { return x.Cmp(m) > 0 }, "%s is greater than maximum %s")
+	check("exclusiveMinimum", func(m *big.Rat) bool { return x.Cmp(m) <= 0 }, "%s must be greater than %s")
+	check("exclusiveMaximum", func(m *big.Rat) bool { return x.Cmp(m) >= 0 }, "%s must be less than %s")
+	check("multipleOf", func(m *big.Rat) bool {
+		return m.Sign() > 0 && !new(big.Rat).Quo(x, m).IsInt()
+	}, "%s is not a multiple of %s")
+	return errs
+}
+
+func (v *schemaValidator) validateArray(s map[string]any, val []any, pointer string) []schemaError {
+	var errs []schemaError
+	add := func(format string, args ...any) {
+		errs = append(errs, schemaError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if limit, ok := schemaInt(s["minItems"]); ok && len(val) < limit {
+		add("array has %d items, fewer than minItems %d", len(val), limit)
+	}
+	if limit, ok := schemaInt(s["maxItems"]); ok && len(val) > limit {
+		add("array has %d items, more than maxItems %d", len(val), limit)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+	duplicate:
+		for i := range val {
+			for j := i + 1; j < len(val); j++ {
+				if jsonEqual(val[i], val[j]) {
+					add("items at index %d and %d are equal, uniqueItems is required", i, j)
+					break duplicate
+				}
+			}
+		}
+	}
+
+	// prefixItems 校验前面的元素，items 校验其余的元素
+	prefix := 0
+	if items, ok := s["prefixItems"].([]any); ok {
+		prefix = len(items)
+		for i, sub := range items[:min(len(items), len(val))] {
+			errs = append(errs, v.validate(sub, val[i], pointer+"/"+strconv.Itoa(i))...)
+		}
+	}
+	if sub, ok := s["items"]; ok {
+		for i := prefix; i < len(val); i++ {
+			errs = append(errs, v.validate(sub, val[i], pointer+"/"+strconv.Itoa(i))...)
+		}
+	}
+
+	if sub, ok := s["contains"]; ok {
+		count := 0
+		for i, item := range val {
+			if v.matches(sub, item, pointer+"/"+strconv.Itoa(i)) {
+				count++
+			}
+		}
+		minContains, ok := schemaInt(s["minContains"])
+		if !ok {
+			minContains = 1
+		}
+		if count < minContains {
+			add("array contains %d matching items, fewer than %d", count, minContains)
+		}
+		if limit, ok := schemaInt(s["maxContains"]); ok && count > limit {
+			add("array contains %d matching items, more than maxContains %d", count, limit)
+		}
+	}
+	return errs
+}
+
+func (v *schemaValidator) validateObject(s map[string]any, val map[string]any, pointer string) []schemaError {
+	var errs []schemaError
+	add := func(format string, args ...any) {
+		errs = append(errs, schemaError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if limit, ok := schemaInt(s["minProperties"]); ok && len(val) < limit {
+		add("object has %d properties, fewer than minProperties %d", len(val), limit)
+	}
+	if limit, ok := schemaInt(s["maxProperties"]); ok && len(val) > limit {
+		add("object has %d properties, more than maxProperties %d", len(val), limit)
+	}
+	if required, ok := s["required"].([]any); ok {
+		for _, r := range required {
+			if key, ok := r.(string); ok {
+				if _, present := val[key]; !present {
+					add("missing required property %q", key)
+				}
+			}
+		}
+	}
+	if deps, ok := s["dependentRequired"].(map[string]any); ok {
+		for _, key := range sortedKeys(deps) {
+			if _, present := val[key]; !present {
+				continue
+			}
+			required, _ := deps[key].([]any)
+			for _, r := range required {
+				if dep, ok := r.(string); ok {
+					if _, present := val[dep]; !present {
+						add("property %q is required when %q is present", dep, key)
+					}
+				}
+			}
+		}
+	}
+	if deps, ok := s["dependentSchemas"].(map[string]any); ok {
+		for _, key := range sortedKeys(deps) {
+			if _, present := val[key]; present {
+				errs = append(errs, v.validate(deps[key], val, pointer)...)
+			}
+		}
+	}
+
+	properties, _ := s["properties"].(map[string]any)
+	patternProperties, _ := s["patternProperties"].(map[string]any)
+	additional, hasAdditional := s["additionalProperties"]
+	names, hasNames := s["propertyNames"]
+	for _, key := range sortedKeys(val) {
+		child := pointer + "/" + jsonPointerEscape(key)
+		if hasNames && !v.matches(names, key, child) {
+			errs = append(errs, schemaError{Pointer: child, Message: fmt.Sprintf("property name %q does not match propertyNames", key)})
+		}
+
+		matched := false
+		if sub, ok := properties[key]; ok {
+			matched = true
+			errs = append(errs, v.validate(sub, val[key], child)...)
+		}
+		for _, expr := range sortedKeys(patternProperties) {
+			if re := v.pattern(expr); re != nil && re.MatchString(key) {
+				matched = true
+				errs = append(errs, v.validate(patternProperties[expr], val[key], child)...)
+			}
+		}
+		if matched || !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			errs = append(errs, schemaError{Pointer: child, Message: fmt.Sprintf("additional property %q is not allowed", key)})
+			continue
+		}
+		errs = append(errs, v.validate(additional, val[key], child)...)
+	}
+	return errs
+}
+
+// sortedKeys 返回排序后的键，使错误的顺序不受 map 遍历顺序影响
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// jsonValueType 返回值的 JSON Schema 类型名，没有小数部分的数字是 integer
+func jsonValueType(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if x, ok := jsonRat(val); ok && x.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+// schemaTypeMatches 判断值是否属于 type 关键字列出的类型之一，integer 也是 number
+func schemaTypeMatches(t, value any) bool {
+	actual := jsonValueType(value)
+	match := func(name any) bool {
+		return name == actual || (name == "number" && actual == "integer")
+	}
+	if list, ok := t.([]any); ok {
+		return slices.ContainsFunc(list, match)
+	}
+	return match(t)
+}
+
+// schemaTypeList 把 type 关键字写成 "string or null" 的形式
+func schemaTypeList(t any) string {
+	list, ok := t.([]any)
+	if !ok {
+		return fmt.Sprint(t)
+	}
+	names := make([]string, len(list))
+	for i, name := range list {
+		names[i] = fmt.Sprint(name)
+	}
+	return strings.Join(names, " or ")
+}
+
+// schemaInt 读取非负整数的关键字，如 minLength
+func schemaInt(value any) (int, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	x, ok := jsonRat(n)
+	if !ok || !x.IsInt() || x.Sign() < 0 || !x.Num().IsInt64() {
+		return 0, false
+	}
+	return int(x.Num().Int64()), true
+}
+
+// jsonRat 把数字转换为精确的有理数。指数过大时精确计算代价太高，退回 float64
+func jsonRat(n json.Number) (*big.Rat, bool) {
+	s := n.String()
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		if exp, err := strconv.Atoi(s[i+1:]); err != nil || exp > 400 || exp < -400 {
+			f, err := n.Float64()
+			if err != nil {
+				return nil, false
+			}
+			return new(big.Rat).SetFloat64(f), true
+		}
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// jsonEqual 按 JSON 的语义比较两个值，数字比较数值（1 与 1.0 相等），对象不考虑键的顺序
+func jsonEqual(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		rx, okx := jsonRat(x)
+		ry, oky := jsonRat(y)
+		return okx && oky && rx.Cmp(ry) == 0
+	case []any:
+		y, ok := b.([]any)
+		return ok && slices.EqualFunc(x, y, jsonEqual)
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, xv := range x {
+			yv, ok := y[key]
+			if !ok || !jsonEqual(xv, yv) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// compactJSONValue 把值写成单行 JSON，用于错误消息，过长时截断
+func compactJSONValue(value any) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(value)
+	s := strings.TrimSuffix(b.String(), "\n")
+	if utf8.RuneCountInString(s) > 80 {
+		s = string([]rune(s)[:77]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// jsonKind 是推断出的 JSON 值类型
+type jsonKind int
+
+const (
+	// jsonKindNull 表示只出现过 null
+	jsonKindNull jsonKind = iota
+	jsonKindBool
+	jsonKindInt
+	jsonKindFloat
+	jsonKindString
+	jsonKindObject
+	jsonKindArray
+	// jsonKindAny 表示同一位置出现了不兼容的类型
+	jsonKindAny
+)
+
+// jsonType 是从样本推断出的类型。同一位置出现的所有值（包括数组的全部元素）合并为一个类型
+type jsonType struct {
+	Kind jsonKind
+	// Nullable 表示这个位置出现过 null
+	Nullable bool
+	// Fields 是对象的字段，按键第一次出现的顺序排列
+	Fields []*jsonField
+	// Elem 是数组元素的类型，空数组为 nil
+	Elem *jsonType
+	// Name 是对象类型的名称，由 nameJSONTypes 分配
+	Name string
+
+	sig string
+}
+
+// jsonField 是对象类型的一个字段
+type jsonField struct {
+	Key  string
+	Type *jsonType
+	// Optional 表示有的对象中缺少这个字段
+	Optional bool
+}
+
+// inferJSONType 从解析后的 JSON 推断类型。整数超出 int64 范围时按小数处理
+func inferJSONType(n *jsonNode) *jsonType {
+	switch n.kind {
+	case '{':
+		t := &jsonType{Kind: jsonKindObject}
+		index := make(map[string]int, len(n.keys))
+		for i, key := range n.keys {
+			child := inferJSONType(n.values[i])
+			// 重复的键合并为一个字段
+			if j, ok := index[key]; ok {
+				t.Fields[j].Type = mergeJSONTypes(t.Fields[j].Type, child)
+				continue
+			}
+			index[key] = len(t.Fields)
+			t.Fields = append(t.Fields, &jsonField{Key: key, Type: child})
+		}
+		return t
+	case '[':
+		t := &jsonType{Kind: jsonKindArray}
+		for _, v := range n.values {
+			t.Elem = mergeJSONTypes(t.Elem, inferJSONType(v))
+		}
+		return t
+	}
+
+	switch lit := n.literal; {
+	case lit == "null":
+		return &jsonType{Kind: jsonKindNull, Nullable: true}
+	case lit == "true" || lit == "false":
+		return &jsonType{Kind: jsonKindBool}
+	case strings.HasPrefix(lit, `"`):
+		return &jsonType{Kind: jsonKindString}
+	case strings.ContainsAny(lit, ".eE"):
+		return &jsonType{Kind: jsonKindFloat}
+	default:
+		if _, err := strconv.ParseInt(lit, 10, 64); err != nil {
+			return &jsonType{Kind: jsonKindFloat}
+		}
+		return &jsonType{Kind: jsonKindInt}
+	}
+}
+
+// mergeJSONTypes 合并同一位置出现的两个类型，不修改参数。整数和小数合并为小数，null 使类型可空，
+// 对象合并字段，只在一边出现的字段是可选的，数组合并元素类型，其他不同的类型合并为 any
+func mergeJSONTypes(a, b *jsonType) *jsonType {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	var merged jsonType
+	switch {
+	case a.Kind == jsonKindNull:
+		merged = *b
+	case b.Kind == jsonKindNull:
+		merged = *a
+	case a.Kind == b.Kind && a.Kind == jsonKindObject:
+		merged = *mergeJSONObjects(a, b)
+	case a.Kind == b.Kind && a.Kind == jsonKindArray:
+		merged = jsonType{Kind: jsonKindArray, Elem: mergeJSONTypes(a.Elem, b.Elem)}
+	case a.Kind == b.Kind:
+		merged = jsonType{Kind: a.Kind}
+	case (a.Kind == jsonKindInt || a.Kind == jsonKindFloat) && (b.Kind == jsonKindInt || b.Kind == jsonKindFloat):
+		merged = jsonType{Kind: jsonKindFloat}
+	default:
+		merged = jsonType{Kind: jsonKindAny}
+	}
+	merged.Nullable = a.Nullable || b.Nullable
+	merged.sig = ""
+	return &merged
+}
+
+func mergeJSONObjects(a, b *jsonType) *jsonType {
+	t := &jsonType{Kind: jsonKindObject}
+	inA := make(map[string]bool, len(a.Fields))
+	inB := make(map[string]*jsonField, len(b.Fields))
+	for _, f := range a.Fields {
+		inA[f.Key] = true
+	}
+	for _, f := range b.Fields {
+		inB[f.Key] = f
+	}
+	for _, f := range a.Fields {
+		if g, ok := inB[f.Key]; ok {
+			t.Fields = append(t.Fields, &jsonField{Key: f.Key, Type: mergeJSONTypes(f.Type, g.Type), Optional: f.Optional || g.Optional})
+		} else {
+			t.Fields = append(t.Fields, &jsonField{Key: f.Key, Type: f.Type, Optional: true})
+		}
+	}
+	for _, g := range b.Fields {
+		if !inA[g.Key] {
+			t.Fields = append(t.Fields, &jsonField{Key: g.Key, Type: g.Type, Optional: true})
+		}
+	}
+	return t
+}
+
+// signature 返回类型结构的描述，结构相同的对象共用一个类型名
+func (t *jsonType) signature() string {
+	if t == nil {
+		return "-"
+	}
+	if t.sig != "" {
+		return t.sig
+	}
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(int(t.Kind)))
+	if t.Nullable {
+		b.WriteByte('?')
+	}
+	switch t.Kind {
+	case jsonKindObject:
+		b.WriteByte('{')
+		for _, f := range t.Fields {
+			b.WriteString(strconv.Quote(f.Key))
+			if f.Optional {
+				b.WriteByte('?')
+			}
+			b.WriteByte(':')
+			b.WriteString(f.Type.signature())
+			b.WriteByte(',')
+		}
+		b.WriteByte('}')
+	case jsonKindArray:
+		b.WriteByte('[')
+		b.WriteString(t.Elem.signature())
+		b.WriteByte(']')
+	}
+	t.sig = b.String()
+	return t.sig
+}
+
+// nameJSONTypes 给对象类型分配名称，返回需要输出的对象类型，根类型在前，其余按第一次出现的顺序排列。
+// ident 把键转换为类型名；结构相同的对象共用一个名称，名称冲突时依次尝试加上父类型名和数字后缀。
+// reserved 是生成的代码导入或依赖的名称（如 Java 的 List、Rust 的 Option），不分配给对象类型
+func nameJSONTypes(root *jsonType, rootName string, ident func(string) string, reserved map[string]bool) []*jsonType {
+	n := &jsonTypeNamer{ident: ident, used: make(map[string]string)}
+	for name := range reserved {
+		// 签名不会是空字符串，保留的名称不会被复用
+		n.used[name] = ""
+	}
+	if root.Kind == jsonKindObject {
+		n.assign(root, rootName, "")
+	} else {
+		// 根类型不是对象时，rootName 留给根类型的别名
+		n.used[rootName] = ""
+		n.assign(root, rootName+"Item", "")
+	}
+	return n.types
+}
+
+type jsonTypeNamer struct {
+	ident func(string) string
+	// used 是已分配的名称和对应的结构
+	used  map[string]string
+	types []*jsonType
+}
+
+func (n *jsonTypeNamer) assign(t *jsonType, name, parent string) {
+	if t == nil {
+		return
+	}
+	switch t.Kind {
+	case jsonKindArray:
+		n.assign(t.Elem, singularName(name), parent)
+	case jsonKindObject:
+		sig := t.signature()
+		for i := 0; ; i++ {
+			candidate := name
+			switch {
+			case i == 1 && parent != "":
+				candidate = parent + name
+			case i == 1:
+				continue
+			case i > 1:
+				candidate = name + strconv.Itoa(i)
+			}
+			existing, ok := n.used[candidate]
+			if ok && existing == sig {
+				// 与已有的类型结构相同，直接复用
+				t.Name = candidate
+				return
+			}
+			if !ok {
+				t.Name = candidate
+				break
+			}
+		}
+		n.used[t.Name] = sig
+		n.types = append(n.types, t)
+		for _, f := range t.Fields {
+			n.assign(f.Type, n.ident(f.Key), t.Name)
+		}
+	}
+}
+
+// singularName 把复数名称变为单数，用作数组元素的类型名
+func singularName(name string) string {
+	switch {
+	case strings.HasSuffix(name, "Children"):
+		return strings.TrimSuffix(name, "ren")
+	case strings.HasSuffix(name, "People"):
+		return strings.TrimSuffix(name, "People") + "Person"
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "sses") || strings.HasSuffix(name, "xes") ||
+		strings.HasSuffix(name, "ches") || strings.HasSuffix(name, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "s") && len(name) > 1 &&
+		!strings.HasSuffix(name, "ss") && !strings.HasSuffix(name, "us") && !strings.HasSuffix(name, "is"):
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// jsonKeyWords 把键拆分为单词：字母和数字以外的字符是分隔符，小写到大写、数字到字母是单词边界，
+// 连续的大写字母作为一个单词（HTTPServer 拆分为 HTTP 和 Server）
+func jsonKeyWords(key string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = cur[:0]
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(cur) > 0 {
+			prev := cur[len(cur)-1]
+			switch {
+			case unicode.IsLower(prev) && unicode.IsUpper(r),
+				unicode.IsDigit(prev) && !unicode.IsDigit(r),
+				unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+// digitNames 用于以数字开头的键，标识符不能以数字开头
+var digitNames = [...]string{"Zero", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine"}
+
+// pascalCase 把键转换为首字母大写的标识符。initialisms 中的单词全部大写，
+// 以数字开头时第一个数字改为英文单词，首字母没有大小写（例如中文）时加上前缀 X，没有可用字符时返回 Field
+func pascalCase(key string, initialisms map[string]bool) string {
+	var b strings.Builder
+	for _, w := range jsonKeyWords(key) {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(strings.ToLower(w))
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	name := b.String()
+	if name == "" {
+		return "Field"
+	}
+	first := []rune(name)[0]
+	switch {
+	case '0' <= first && first <= '9':
+		return digitNames[first-'0'] + name[1:]
+	case !unicode.IsUpper(first):
+		return "X" + name
+	}
+	return name
+}
+
+// camelCase 把键转换为首字母小写的驼峰标识符，不处理缩写
+func camelCase(key string) string {
+	name := []rune(pascalCase(key, nil))
+	if name[0] == 'X' && len(name) > 1 && !unicode.IsUpper(name[1]) && !unicode.IsLower(name[1]) {
+		// 中文等没有大小写的名称不需要前缀
+		return string(name[1:])
+	}
+	name[0] = unicode.ToLower(name[0])
+	return string(name)
+}
+
+// snakeCase 把键转换为小写下划线分隔的标识符，以数字开头时第一个数字改为英文单词
+func snakeCase(key string) string {
+	words := jsonKeyWords(key)
+	if len(words) == 0 {
+		return "field"
+	}
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	if first := words[0][0]; '0' <= first && first <= '9' {
+		words[0] = strings.ToLower(digitNames[first-'0']) + words[0][1:]
+	}
+	return strings.Join(words, "_")
+}
+
+// inferJSONInput 解析 JSON 文本并推断类型
+func inferJSONInput(input string) (*jsonType, error) {
+	node, err := parseOrderedJSON(input)
+	if err != nil {
+		return nil, err
+	}
+	return inferJSONType(node), nil
+}
+
+// dependencyOrder 重新排列对象类型，被引用的类型排在引用它的类型之前，用于要求先定义后使用的语言
+func dependencyOrder(types []*jsonType) []*jsonType {
+	// 结构相同而共用名称的对象只有第一个分配了字段类型的名称，按名称找到它
+	byName := make(map[string]*jsonType, len(types))
+	for _, t := range types {
+		byName[t.Name] = t
+	}
+	ordered := make([]*jsonType, 0, len(types))
+	visited := make(map[string]bool, len(types))
+	var visit func(t *jsonType)
+	visit = func(t *jsonType) {
+		switch {
+		case t == nil:
+		case t.Kind == jsonKindArray:
+			visit(t.Elem)
+		case t.Kind == jsonKindObject && !visited[t.Name]:
+			visited[t.Name] = true
+			t = byName[t.Name]
+			for _, f := range t.Fields {
+				visit(f.Type)
+			}
+			ordered = append(ordered, t)
+		}
+	}
+	for _, t := range types {
+		visit(t)
+	}
+	return ordered
+}
@@ -0,0 +1,213 @@
+package tools
+
+import (
+	"c2v2/internal/pkg/qrcode"
+	"c2v2/internal/pkg/render"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	qrDefaultSize  = 256
+	qrMinSize      = 64
+	qrMaxSize      = 2048
+	qrMaxQuietZone = 16
+	// qrMaxText 明显超过版本 40 的最大容量（2953 字节），更长的请求不再尝试编码
+	qrMaxText = 4096
+)
+
+var errQRTooLong = errors.New("text too long for a QR code")
+
+// qrOptions 是二维码图片的参数
+type qrOptions struct {
+	Level     qrcode.Level
+	Size      int
+	QuietZone int
+	// Format 是 svg 或 png
+	Format string
+}
+
+// parseQROptions 读取查询参数 ec（纠错级别 L/M/Q/H）、size（边长像素）、margin（静区模块数）和 format（svg/png）
+func parseQROptions(c *gin.Context) (qrOptions, error) {
+	opts := qrOptions{Size: qrDefaultSize, QuietZone: qrcode.DefaultQuietZone, Format: "svg"}
+
+	level, err := qrcode.ParseLevel(c.Query("ec"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Level = level
+
+	if s := c.Query("size"); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil || size < qrMinSize || size > qrMaxSize {
+			return opts, errors.New("size must be between 64 and 2048")
+		}
+		opts.Size = size
+	}
+	if s := c.Query("margin"); s != "" {
+		margin, err := strconv.Atoi(s)
+		if err != nil || margin < 0 || margin > qrMaxQuietZone {
+			return opts, errors.New("margin must be between 0 and 16")
+		}
+		opts.QuietZone = margin
+	}
+	switch format := strings.ToLower(c.Query("format")); format {
+	case "":
+	case "svg", "png":
+		opts.Format = format
+	default:
+		return opts, errors.New("format must be svg or png")
+	}
+	return opts, nil
+}
+
+// query 把参数写回查询字符串，用于生成图片链接
+func (o qrOptions) query(text string) url.Values {
+	return url.Values{
+		"text":   {text},
+		"ec":     {o.Level.String()},
+		"size":   {strconv.Itoa(o.Size)},
+		"margin": {strconv.Itoa(o.QuietZone)},
+		"format": {o.Format},
+	}
+}
+
+// writeQRCode 把文本编码为二维码图片并写入响应。相同参数的输出总是相同，允许缓存
+func writeQRCode(c *gin.Context, text string, opts qrOptions) {
+	if len(text) > qrMaxText {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errQRTooLong.Error()})
+		return
+	}
+	code, err := qrcode.Encode([]byte(text), opts.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errQRTooLong.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	if opts.Format == "png" {
+		data, err := code.PNG(opts.Size, opts.QuietZone)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render image"})
+			return
+		}
+		c.Data(http.StatusOK, "image/png", data)
+		return
+	}
+	c.Data(http.StatusOK, "image/svg+xml", code.SVG(opts.Size, opts.QuietZone))
+}
+
+type QRCodeTool struct {
+	Render *render.Helper
+}
+
+func NewQRCodeTool(r *render.Helper) *QRCodeTool {
+	return &QRCodeTool{Render: r}
+}
+
+// HandleImage 生成任意文本的二维码图片：GET /api/qr?text=...&ec=M&size=256&margin=4&format=svg
+func (t *QRCodeTool) HandleImage(c *gin.Context) {
+	opts, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	text := c.Query("text")
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "text is required"})
+		return
+	}
+	writeQRCode(c, text, opts)
+}
+
+// Handler 渲染二维码生成页面。表单以 GET 提交，生成的图片是普通的 <img> 链接，不依赖 JavaScript
+func (t *QRCodeTool) Handler(c *gin.Context) {
+	lang := c.GetString("lang")
+	if lang == "" {
+		lang = "en"
+	}
+
+	text := c.Query("text")
+	opts, err := parseQROptions(c)
+	errorKey := ""
+	switch {
+	case err != nil:
+		errorKey = "qr_error_invalid"
+	case len(text) > qrMaxText:
+		errorKey = "qr_error_too_long"
+	case text != "":
+		if _, err := qrcode.Encode([]byte(text), opts.Level); err != nil {
+			errorKey = "qr_error_too_long"
+		}
+	}
+
+	imageURL, svgURL, pngURL := "", "", ""
+	if text != "" && errorKey == "" {
+		query := opts.query(text)
+		imageURL = "/api/qr?" + query.Encode()
+		query.Set("format", "svg")
+		svgURL = "/api/qr?" + query.Encode()
+		query.Set("format", "png")
+		pngURL = "/api/qr?" + query.Encode()
+	}
+
+	appSchema := map[string]any{
+		"@type":               "SoftwareApplication",
+		"name":                t.Render.Translate(lang, "tool_qr_title"),
+		"applicationCategory": "DeveloperApplication",
+		"operatingSystem":     "Web",
+		"offers": map[string]string{
+			"@type": "Offer",
+			"price": "0",
+		},
+		"description": t.Render.Translate(lang, "tool_qr_desc"),
+	}
+
+	faqSchema := map[string]any{
+		"@type": "FAQPage",
+		"mainEntity": []map[string]any{
+			{
+				"@type": "Question",
+				"name":  t.Render.Translate(lang, "qr_seo_faq_1_q"),
+				"acceptedAnswer": map[string]any{
+					"@type": "Answer",
+					"text":  t.Render.Translate(lang, "qr_seo_faq_1_a"),
+				},
+			},
+			{
+				"@type": "Question",
+				"name":  t.Render.Translate(lang, "qr_seo_faq_2_q"),
+				"acceptedAnswer": map[string]any{
+					"@type": "Answer",
+					"text":  t.Render.Translate(lang, "qr_seo_faq_2_a"),
+				},
+			},
+		},
+	}
+
+	graphSchema := map[string]any{
+		"@context": "https://schema.org",
+		"@graph":   []any{appSchema, faqSchema},
+	}
+
+	t.Render.HTML(c, http.StatusOK, "qr_code.html", gin.H{
+		"title":       "tool_qr_page_title",
+		"description": "tool_qr_page_desc",
+		"keywords":    "tool_qr_keywords",
+		"SchemaData":  graphSchema,
+		"Text":        text,
+		"Level":       opts.Level.String(),
+		"Size":        opts.Size,
+		"Margin":      opts.QuietZone,
+		"Format":      opts.Format,
+		"ImageURL":    imageURL,
+		"SVGURL":      svgURL,
+		"PNGURL":      pngURL,
+		"ErrorKey":    errorKey,
+	})
+}
@@ -31,6 +31,14 @@ var (
 		IconHTML: template.HTML(`<svg class="w-6 h-6" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M8 7h12m0 0l-4-4m4 4l-4 4m0 6H4m0 0l4 4m-4-4l4-4"></path></svg>`),
 	}
 
+	ToolEncoder = Tool{
+		ID:       "encoder",
+		NameKey:  "tool_encoder_title",
+		DescKey:  "tool_encoder_desc",
+		URL:      "/encoder",
+		IconHTML: template.HTML(`<svg class="w-6 h-6" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 8h10M7 12h4m1 8l-4-4H5a2 2 0 01-2-2V6a2 2 0 012-2h14a2 2 0 012 2v8a2 2 0 01-2 2h-3l-4 4z"></path></svg>`),
+	}
+
 	ToolJSON = Tool{
 		ID:       "json-fmt",
 		NameKey:  "tool_json_title",
@@ -77,6 +85,14 @@ var (
 		URL:      "/clipboard",
 		IconHTML: template.HTML(`<svg class="w-6 h-6" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M9 5H7a2 2 0 00-2 2v12a2 2 0 002 2h10a2 2 0 002-2V7a2 2 0 00-2-2h-2M9 5a2 2 0 002 2h2a2 2 0 002-2M9 5a2 2 0 012-2h2a2 2 0 012 2m-3 7h3m-3 4h3m-6-4h.01M9 16h.01"></path></svg>`),
 	}
+
+	ToolQRCode = Tool{
+		ID:       "qr-code",
+		NameKey:  "tool_qr_title",
+		DescKey:  "tool_qr_desc",
+		URL:      "/qr-code",
+		IconHTML: template.HTML(`<svg class="w-6 h-6" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M12 4v1m6 11h2m-6 0h-2v4m0-11v3m0 0h.01M12 12h4.01M16 20h4M4 12h4m12 0h.01M5 8h2a1 1 0 001-1V5a1 1 0 00-1-1H5a1 1 0 00-1 1v2a1 1 0 001 1zm12 0h2a1 1 0 001-1V5a1 1 0 00-1-1h-2a1 1 0 00-1 1v2a1 1 0 001 1zM5 20h2a1 1 0 001-1v-2a1 1 0 00-1-1H5a1 1 0 00-1 1v2a1 1 0 001 1z"></path></svg>`),
+	}
 )
 
 // Categories 返回所有工具分类（用于首页渲染）
@@ -92,7 +108,7 @@ func Categories() []Category {
 			ID:      "encoders",
 			NameKey: "cat_encoders_title",
 			DescKey: "cat_encoders_desc",
-			Tools:   []Tool{ToolBase64, ToolHeic},
+			Tools:   []Tool{ToolBase64, ToolEncoder, ToolHeic},
 		},
 		{
 			ID:      "formatters",
@@ -104,14 +120,14 @@ func Categories() []Category {
 			ID:      "utilities",
 			NameKey: "cat_utilities_title",
 			DescKey: "cat_utilities_desc",
-			Tools:   []Tool{ToolClipboard},
+			Tools:   []Tool{ToolClipboard, ToolQRCode},
 		},
 	}
 }
 
 // AllTools 返回所有工具的扁平列表（用于搜索）
 func AllTools() []Tool {
-	return []Tool{ToolBase64, ToolJSON, ToolHTML, ToolCSS, ToolHeic, ToolPassword, ToolClipboard}
+	return []Tool{ToolBase64, ToolEncoder, ToolJSON, ToolHTML, ToolCSS, ToolHeic, ToolPassword, ToolClipboard, ToolQRCode}
 }
 
 // AllRoutes 返回所有需要包含在 Sitemap 中的路由
@@ -120,12 +136,14 @@ func AllRoutes() []string {
 	return []string{
 		"",                   // 首页
 		"base64",             // Base64 工具
+		"encoder",            // 多种编码的编码解码工具
 		"heic-to-jpg",        // HEIC 转换工具
 		"json-fmt",           // JSON 格式化
 		"html-fmt",           // HTML 格式化
 		"css-fmt",            // CSS 格式化
 		"password-generator", // 密码生成器
 		"clipboard",          // 剪贴板
+		"qr-code",            // 二维码生成器
 		"about",              // 关于页面
 		"privacy",            // 隐私政策
 		"terms",              // 服务条款